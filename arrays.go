@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/label"
+)
+
+// ArrayEncoding specifies how array attributes are set as span tags.
+type ArrayEncoding int
+
+const (
+	// ArrayEncodingJSON sets arrays as JSON-encoded string tags, e.g. `["a","b"]`.
+	// It is the default.
+	ArrayEncodingJSON ArrayEncoding = iota
+
+	// ArrayEncodingFlatten sets each array element as a separate tag, having
+	// its index appended to the key, e.g. "key.0" and "key.1". Numeric
+	// elements are set as metrics.
+	ArrayEncodingFlatten
+
+	// ArrayEncodingJoin sets arrays as string tags holding all elements
+	// joined by Options.ArraySeparator, e.g. "a,b".
+	ArrayEncodingJoin
+)
+
+// defaultArraySeparator specifies the separator used with ArrayEncodingJoin
+// when none is set.
+const defaultArraySeparator = ","
+
+// setArrayTag sets the array value val on s under the given key, using the
+// given encoding and separator.
+func setArrayTag(s *Span, key string, val label.Value, enc ArrayEncoding, sep string) {
+	arr := reflect.ValueOf(val.AsArray())
+	if arr.Kind() != reflect.Array && arr.Kind() != reflect.Slice {
+		setStringTag(s, key, val.Emit())
+		return
+	}
+	switch enc {
+	case ArrayEncodingFlatten:
+		for i := 0; i < arr.Len(); i++ {
+			k := key + "." + strconv.Itoa(i)
+			if f, ok := numericValue(arr.Index(i)); ok {
+				setMetric(s, k, f)
+			} else {
+				setStringTag(s, k, fmt.Sprint(arr.Index(i).Interface()))
+			}
+		}
+	case ArrayEncodingJoin:
+		elems := make([]string, arr.Len())
+		for i := range elems {
+			elems[i] = fmt.Sprint(arr.Index(i).Interface())
+		}
+		setStringTag(s, key, strings.Join(elems, sep))
+	default:
+		b, err := json.Marshal(arr.Interface())
+		if err != nil {
+			// e.g. NaN or infinite floats
+			setStringTag(s, key, val.Emit())
+			return
+		}
+		setStringTag(s, key, string(b))
+	}
+}
+
+// numericValue returns the value of v as a float64, if v holds a number.
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
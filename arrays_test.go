@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/label"
+)
+
+func TestSetArrayTag(t *testing.T) {
+	testSpan := func() *Span {
+		return &Span{
+			Meta:    map[string]string{},
+			Metrics: map[string]float64{},
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		val    label.Value
+		enc    ArrayEncoding
+		meta   map[string]string
+		metric map[string]float64
+	}{
+		"json-string": {
+			val:  label.ArrayValue([]string{"a", "b,c"}),
+			meta: map[string]string{"key": `["a","b,c"]`},
+		},
+		"json-bool": {
+			val:  label.ArrayValue([]bool{true, false}),
+			meta: map[string]string{"key": `[true,false]`},
+		},
+		"json-int64": {
+			val:  label.ArrayValue([]int64{1, 2}),
+			meta: map[string]string{"key": `[1,2]`},
+		},
+		"json-float64": {
+			val:  label.ArrayValue([]float64{0.5, 2}),
+			meta: map[string]string{"key": `[0.5,2]`},
+		},
+		"json-nan": {
+			val:  label.ArrayValue([]float64{math.NaN()}),
+			meta: map[string]string{"key": `[NaN]`},
+		},
+		"json-empty": {
+			val:  label.ArrayValue([]string{}),
+			meta: map[string]string{"key": `[]`},
+		},
+		"flatten-string": {
+			val:  label.ArrayValue([]string{"a", "b"}),
+			enc:  ArrayEncodingFlatten,
+			meta: map[string]string{"key.0": "a", "key.1": "b"},
+		},
+		"flatten-bool": {
+			val:  label.ArrayValue([]bool{true, false}),
+			enc:  ArrayEncodingFlatten,
+			meta: map[string]string{"key.0": "true", "key.1": "false"},
+		},
+		"flatten-int32": {
+			val:    label.ArrayValue([]int32{1, -2}),
+			enc:    ArrayEncodingFlatten,
+			metric: map[string]float64{"key.0": 1, "key.1": -2},
+		},
+		"flatten-uint64": {
+			val:    label.ArrayValue([]uint64{3}),
+			enc:    ArrayEncodingFlatten,
+			metric: map[string]float64{"key.0": 3},
+		},
+		"flatten-float32": {
+			val:    label.ArrayValue([]float32{0.5}),
+			enc:    ArrayEncodingFlatten,
+			metric: map[string]float64{"key.0": 0.5},
+		},
+		"join-string": {
+			val:  label.ArrayValue([]string{"a", "b"}),
+			enc:  ArrayEncodingJoin,
+			meta: map[string]string{"key": "a|b"},
+		},
+		"join-int64": {
+			val:  label.ArrayValue([]int64{1, 2}),
+			enc:  ArrayEncodingJoin,
+			meta: map[string]string{"key": "1|2"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			span := testSpan()
+			setArrayTag(span, "key", tt.val, tt.enc, "|")
+			if tt.meta == nil {
+				tt.meta = map[string]string{}
+			}
+			if tt.metric == nil {
+				tt.metric = map[string]float64{}
+			}
+			eq(span.Meta, tt.meta)
+			eq(span.Metrics, tt.metric)
+		})
+	}
+}
+
+func TestArrayEncodingOptions(t *testing.T) {
+	attrs := []label.KeyValue{label.Array("flags", []string{"beta", "dark-mode"})}
+
+	t.Run("default", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		sd.Attributes = attrs
+		span := ConvertSpan(&sd, Options{})
+		equalFunc(t)(span.Meta["flags"], `["beta","dark-mode"]`)
+	})
+
+	t.Run("join", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		sd.Attributes = attrs
+		span := ConvertSpan(&sd, Options{ArrayEncoding: ArrayEncodingJoin})
+		equalFunc(t)(span.Meta["flags"], "beta,dark-mode")
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		eq := equalFunc(t)
+		sd := *spanPairs["child"].oc
+		sd.Attributes = attrs
+		span := ConvertSpan(&sd, Options{ArrayEncoding: ArrayEncodingFlatten})
+		eq(span.Meta["flags.0"], "beta")
+		eq(span.Meta["flags.1"], "dark-mode")
+		_, ok := span.Meta["flags"]
+		eq(ok, false)
+	})
+
+	t.Run("global-tags", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := ConvertSpan(spanPairs["child"].oc, Options{
+			GlobalTags:     attrs,
+			ArrayEncoding:  ArrayEncodingJoin,
+			ArraySeparator: "|",
+		})
+		eq(span.Meta["flags"], "beta|dark-mode")
+	})
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel/label"
+)
+
+// AttributeRules specifies how span attributes are mapped to Datadog span tags.
+// All the rules match the original attribute key.
+type AttributeRules struct {
+	// Rename maps attribute keys to the tag keys they should be renamed to,
+	// e.g. "net.peer.name" to "out.host".
+	Rename map[string]string
+
+	// StringKeys lists the keys of attributes which are always set as string
+	// tags, even when their value is numeric, e.g. IDs. Arrays are encoded as
+	// specified by ArrayEncoding regardless.
+	StringKeys []string
+
+	// DropPrefixes lists the key prefixes of attributes which are dropped.
+	DropPrefixes []string
+}
+
+// attributeRules holds the compiled form of AttributeRules.
+type attributeRules struct {
+	rename       map[string]string
+	stringKeys   map[string]bool
+	dropPrefixes []string
+}
+
+// compile returns the compiled form of the rules, or nil if there are none.
+func (r AttributeRules) compile() *attributeRules {
+	if len(r.Rename) == 0 && len(r.StringKeys) == 0 && len(r.DropPrefixes) == 0 {
+		return nil
+	}
+	ar := &attributeRules{
+		rename:       make(map[string]string, len(r.Rename)),
+		stringKeys:   make(map[string]bool, len(r.StringKeys)),
+		dropPrefixes: append([]string(nil), r.DropPrefixes...),
+	}
+	for from, to := range r.Rename {
+		ar.rename[from] = to
+	}
+	for _, k := range r.StringKeys {
+		ar.stringKeys[k] = true
+	}
+	return ar
+}
+
+// apply applies the rules to the attribute having the given key and value. It
+// returns the resulting key and value, and false if the attribute is dropped.
+func (ar *attributeRules) apply(key string, val label.Value) (string, label.Value, bool) {
+	if ar == nil {
+		return key, val, true
+	}
+	for _, prefix := range ar.dropPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return "", val, false
+		}
+	}
+	if t := val.Type(); ar.stringKeys[key] && t != label.STRING && t != label.ARRAY {
+		val = label.StringValue(val.Emit())
+	}
+	if to, ok := ar.rename[key]; ok {
+		key = to
+	}
+	return key, val, true
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestAttributeRules(t *testing.T) {
+	rules := AttributeRules{
+		Rename: map[string]string{
+			"net.peer.name": ext.TargetHost,
+			"renamed":       "new",
+			"user.id":       "usr.id",
+		},
+		StringKeys:   []string{"http.status_code", "renamed", "user.id"},
+		DropPrefixes: []string{"http.request.header.", "internal."},
+	}
+	e := newTraceExporter(Options{Service: "my-service", AttributeRules: rules})
+	defer e.stop()
+
+	for name, tt := range map[string]struct {
+		attr   label.KeyValue
+		meta   map[string]string  // expected meta, in addition to the status ones
+		metric map[string]float64 // expected metrics
+	}{
+		"string":             {attr: label.String("str", "abc"), meta: map[string]string{"str": "abc"}},
+		"string-renamed":     {attr: label.String("net.peer.name", "db.local"), meta: map[string]string{ext.TargetHost: "db.local"}},
+		"string-dropped":     {attr: label.String("http.request.header.cookie", "abc")},
+		"bool":               {attr: label.Bool("bool", true), meta: map[string]string{"bool": "true"}},
+		"bool-renamed":       {attr: label.Bool("renamed", false), meta: map[string]string{"new": "false"}},
+		"int32":              {attr: label.Int32("int32", 1), metric: map[string]float64{"int32": 1}},
+		"int32-string":       {attr: label.Int32("http.status_code", 404), meta: map[string]string{"http.status_code": "404"}},
+		"int64":              {attr: label.Int64("int64", 2), metric: map[string]float64{"int64": 2}},
+		"int64-string":       {attr: label.Int64("user.id", 1234567890123), meta: map[string]string{"usr.id": "1234567890123"}},
+		"int64-dropped":      {attr: label.Int64("internal.counter", 3)},
+		"uint32":             {attr: label.Uint32("uint32", 3), metric: map[string]float64{"uint32": 3}},
+		"uint32-string":      {attr: label.Uint32("http.status_code", 200), meta: map[string]string{"http.status_code": "200"}},
+		"uint64":             {attr: label.Uint64("uint64", 4), metric: map[string]float64{"uint64": 4}},
+		"uint64-string":      {attr: label.Uint64("user.id", 18446744073709551615), meta: map[string]string{"usr.id": "18446744073709551615"}},
+		"float32":            {attr: label.Float32("float32", 0.5), metric: map[string]float64{"float32": 0.5}},
+		"float32-renamed":    {attr: label.Float32("renamed", 0.5), meta: map[string]string{"new": "0.5"}},
+		"float64":            {attr: label.Float64("float64", 1.5), metric: map[string]float64{"float64": 1.5}},
+		"float64-string":     {attr: label.Float64("http.status_code", 500), meta: map[string]string{"http.status_code": "500"}},
+		"array-dropped":      {attr: label.Array("internal.flags", []string{"a"})},
+		"array-renamed":      {attr: label.Array("renamed", []int64{1, 2}), meta: map[string]string{"new": "[1,2]"}},
+		"special-renamed":    {attr: label.String("user.id", "42"), meta: map[string]string{"usr.id": "42"}},
+		"prefix-not-dropped": {attr: label.String("internal", "abc"), meta: map[string]string{"internal": "abc"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			sd := *spanPairs["child"].oc
+			sd.Attributes = []label.KeyValue{tt.attr}
+			span := e.convertSpan(&sd)
+			want := map[string]string{
+				keyTraceIDHigh: "0102030405060708",
+				keyStatus:      "OK",
+				keyStatusCode:  "0",
+			}
+			for k, v := range tt.meta {
+				want[k] = v
+			}
+			if tt.metric == nil {
+				tt.metric = map[string]float64{}
+			}
+			eq(span.Meta, want)
+			eq(span.Metrics, tt.metric)
+		})
+	}
+}
+
+func TestAttributeRulesCompile(t *testing.T) {
+	eq := equalFunc(t)
+	if (AttributeRules{}).compile() != nil {
+		t.Fatal("expected no rules")
+	}
+
+	// the compiled rules should not change along with the options
+	rename := map[string]string{"a": "b"}
+	ar := AttributeRules{Rename: rename}.compile()
+	rename["a"] = "c"
+	key, _, ok := ar.apply("a", label.StringValue("v"))
+	eq(ok, true)
+	eq(key, "b")
+
+	var nilRules *attributeRules
+	key, val, ok := nilRules.apply("a", label.Int64Value(1))
+	eq(ok, true)
+	eq(key, "a")
+	eq(val, label.Int64Value(1))
+}
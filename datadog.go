@@ -8,8 +8,10 @@ package datadog
 import (
 	"context"
 	"log"
+	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
@@ -63,26 +65,48 @@ type Options struct {
 	// Namespace specifies the namespaces to which metric keys are appended.
 	Namespace string
 
-	// Service specifies the service name used for tracing.
+	// Service specifies the service name used for tracing. It defaults to the
+	// DD_SERVICE environment variable and is overridden by the "service.name"
+	// attribute of the span or of its resource.
 	Service string
 
+	// Env specifies the environment used for tracing and metrics. It defaults
+	// to the DD_ENV environment variable. On spans, it is overridden by
+	// the "env" or "deployment.environment" attribute of the span, or by the
+	// "deployment.environment" attribute of its resource.
+	Env string
+
+	// Version specifies the application version used for tracing and metrics.
+	// It defaults to the DD_VERSION environment variable. On spans, it is
+	// overridden by the "service.version" attribute of the span or of its
+	// resource.
+	Version string
+
 	// TraceAddr specifies the host[:port] address of the Datadog Trace Agent.
-	// It defaults to localhost:8126.
+	// It defaults to the host of the DD_TRACE_AGENT_URL environment variable
+	// when it is an "http" URL, or else to the DD_AGENT_HOST and
+	// DD_TRACE_AGENT_PORT environment variables, or else to localhost:8126.
 	TraceAddr string
 
 	// StatsAddr specifies the host[:port] address for DogStatsD. It defaults
-	// to localhost:8125.
+	// to the DD_DOGSTATSD_URL environment variable, or else to the
+	// DD_AGENT_HOST and DD_DOGSTATSD_PORT environment variables, or else to
+	// localhost:8125.
 	StatsAddr string
 
 	// OnError specifies a function that will be called if an error occurs during
 	// processing stats or metrics.
 	OnError func(err error)
 
-	// Tags specifies a set of global tags to attach to each metric.
+	// Tags specifies a set of global tags to attach to each metric. The tags
+	// found in the DD_TAGS environment variable, as well as the env and version
+	// tags of the explicit Env and Version, are added to them unless already
+	// present. The statsd client adds the ones of DD_ENV and DD_VERSION itself.
 	Tags []string
 
 	// GlobalTags holds a set of tags that will automatically be applied to all
-	// exported spans.
+	// exported spans. They take precedence over the tags found in the DD_TAGS
+	// environment variable.
 	GlobalTags []label.KeyValue
 
 	// DisableCountPerBuckets specifies whether to emit count_per_bucket metrics
@@ -90,8 +114,184 @@ type Options struct {
 
 	// TagMetricNames specifies whether to include tags to metric names.
 	TagMetricNames bool
+
+	// TraceIDMapping specifies how 128-bit OpenTelemetry trace IDs are mapped
+	// to 64-bit Datadog trace IDs. It defaults to TraceIDLow64.
+	TraceIDMapping TraceIDMapping
+
+	// AttributeRules specifies rules for renaming, dropping and forcing the
+	// type of span attributes before they are set as Datadog span tags.
+	AttributeRules AttributeRules
+
+	// ArrayEncoding specifies how array attributes are set as span tags. It
+	// defaults to ArrayEncodingJSON.
+	ArrayEncoding ArrayEncoding
+
+	// ArraySeparator specifies the separator used for joining array elements
+	// when ArrayEncoding is ArrayEncodingJoin. It defaults to ",".
+	ArraySeparator string
+
+	// ScrubKeys specifies the patterns matching the keys of sensitive span
+	// attributes, whose values are replaced by "?". A key matches when it
+	// contains any of the patterns, regardless of case. It defaults to a list
+	// including "password", "token", "authorization" and "cookie".
+	ScrubKeys []string
+
+	// DisableScrubbing disables the redaction of sensitive data from span
+	// attributes. Unless disabled, the attributes matching ScrubKeys are
+	// masked, query strings are removed from "http.url" and credentials are
+	// redacted from URLs and "db.connection_string".
+	DisableScrubbing bool
+
+	// DisableQueryObfuscation disables the quantization of database queries.
+	// Unless disabled, the literals of the SQL queries, Redis commands and
+	// Mongo queries found in resource names and in the "db.statement" tag are
+	// replaced by "?". The queries of other database systems are sent as is.
+	DisableQueryObfuscation bool
+
+	// ErrorClassifier specifies the function deciding whether a span is an
+	// error, based on its status, kind and events. Only the "error" attribute
+	// takes precedence. It defaults to DefaultErrorClassifier.
+	ErrorClassifier ErrorClassifier
+
+	// ResourceNamer specifies a function which returns the resource name of
+	// the given span. When it is nil or returns an empty string, the resource
+	// name is derived from the HTTP, RPC and database attributes of the span,
+	// falling back to the span name. The "resource.name" attribute always takes
+	// precedence.
+	ResourceNamer func(s *trace.SpanData) string
+
+	// Measured specifies a function which reports whether the given span should
+	// be measured, meaning that the agent computes trace metrics (hits, errors
+	// and latency) for it even though it is not top-level. The "_dd.measured"
+	// attribute always takes precedence.
+	Measured func(s *trace.SpanData) bool
+
+	// SpanProcessors specifies a chain of functions which are run, in order,
+	// on each converted span before it is sampled and encoded. Processors may
+	// modify the span, or drop it by returning false, in which case the rest
+	// of the chain is skipped. Processors are never called concurrently.
+	SpanProcessors []SpanProcessor
+
+	// MaxEvents specifies the maximum number of span events that will be
+	// exported with each span. It defaults to 128. A negative value disables
+	// the export of span events.
+	MaxEvents int
+
+	// MaxEventsSize specifies the maximum size in bytes of the JSON-encoded
+	// span events attached to each span. Events which do not fit are dropped.
+	// It defaults to 25000, which is the agent's limit for a meta value.
+	MaxEventsSize int
+
+	// MaxLinksSize specifies the maximum size in bytes of the JSON-encoded
+	// span links attached to each span. Links which do not fit are dropped.
+	// It defaults to 25000, which is the agent's limit for a meta value.
+	MaxLinksSize int
+
+	// MaxTagKeyLength specifies the maximum length in bytes of span tag and
+	// metric keys. The entries whose truncated key is already taken are
+	// dropped. It defaults to 200.
+	MaxTagKeyLength int
+
+	// MaxTagValueLength specifies the maximum length in bytes of span tag
+	// values. It does not apply to the JSON-encoded span events and links,
+	// which are bounded by MaxEventsSize and MaxLinksSize instead. It defaults
+	// to 25000.
+	MaxTagValueLength int
+
+	// MaxMetaEntries specifies the maximum number of tags of a span. The tags
+	// exceeding it are dropped in reverse key order, except for the reserved
+	// env, version, error.* and _dd.* tags. It defaults to 1000.
+	MaxMetaEntries int
+
+	// MaxResourceLength specifies the maximum length in bytes of span resource
+	// names. It defaults to 5000.
+	MaxResourceLength int
+
+	// MaxServiceLength specifies the maximum length in bytes of span service
+	// names. It defaults to 100.
+	//
+	// Values exceeding any of the above length limits are truncated and end
+	// with "...". The number of truncated values and dropped tags of a span are
+	// recorded as metrics. A negative value disables the corresponding limit.
+	MaxServiceLength int
+
+	// SamplingRules specifies the rules deciding the sampling priority of the
+	// traces whose spans match them. They are evaluated in order, before the
+	// rates provided by the agent, and the first matching rule applies. They
+	// default to the JSON array found in the DD_TRACE_SAMPLING_RULES
+	// environment variable, e.g.:
+	//
+	//	[{"service": "payment", "sample_rate": 1}, {"name": "*.health", "sample_rate": 0.01}]
+	SamplingRules []SamplingRule
+
+	// TraceRateLimit specifies the maximum number of traces per second which
+	// may be kept by SamplingRules. The traces exceeding it are rejected. It
+	// defaults to the DD_TRACE_RATE_LIMIT environment variable, or else to 100.
+	// A negative value disables the limit.
+	TraceRateLimit float64
+
+	// DecisionCacheSize specifies the maximum number of traces whose sampling
+	// decision is remembered, so that all of their spans get the same sampling
+	// priority. It defaults to 10000.
+	DecisionCacheSize int
+
+	// DecisionCacheTTL specifies the duration for which the sampling decision
+	// of a trace is remembered after the last span of the trace was sampled.
+	// It defaults to one minute.
+	DecisionCacheTTL time.Duration
+
+	// Retention specifies the policy keeping error, slow and large traces
+	// regardless of the sampling rates provided by the agent. It requires
+	// TailSampling, so that the whole trace is kept, and is ignored otherwise.
+	Retention RetentionPolicy
+
+	// TailSampling enables the buffering of spans until the local root span
+	// of their trace is received, so that a single sampling decision is made
+	// using the whole trace and complete traces are sent to the agent. It is
+	// also required for marking the spans whose service differs from the one
+	// of their parent as top-level, so that the agent computes trace metrics
+	// for them; otherwise only local roots are. Likewise, without it, the
+	// sampling priority carried by a span, either set explicitly or propagated
+	// from upstream, is ignored when other spans of its trace were sent before.
+	TailSampling bool
+
+	// TailSamplingTimeout specifies the maximum duration for which the spans
+	// of a trace are buffered when TailSampling is enabled. The trace is sent
+	// as is once it has elapsed. It defaults to 30 seconds.
+	TailSamplingTimeout time.Duration
+
+	// TailSamplingMaxSpans specifies the maximum number of spans which are
+	// buffered when TailSampling is enabled. The oldest traces are sent as
+	// is when it is exceeded. It defaults to 100000.
+	TailSamplingMaxSpans int
+
+	// SpanSamplingRules specifies the rules keeping individual spans of the
+	// traces which are rejected by sampling. They are evaluated in order and
+	// the first matching rule applies. They default to the JSON array found in
+	// the DD_SPAN_SAMPLING_RULES environment variable, e.g.:
+	//
+	//	[{"service": "billing", "name": "db.query", "sample_rate": 0.1, "max_per_second": 50}]
+	SpanSamplingRules []SpanSamplingRule
 }
 
+// SpanProcessor processes a converted Datadog span before it is encoded. It
+// returns false if the span should be dropped.
+type SpanProcessor func(s *Span) bool
+
+// TraceIDMapping specifies how 128-bit OpenTelemetry trace IDs are mapped to
+// the 64-bit trace IDs of Datadog spans.
+type TraceIDMapping int
+
+const (
+	// TraceIDLow64 uses the low 64 bits of the trace ID. The high 64 bits are
+	// carried in the "_dd.p.tid" tag, allowing the full ID to be recovered.
+	TraceIDLow64 TraceIDMapping = iota
+
+	// TraceIDHash uses a 64-bit hash of the full trace ID.
+	TraceIDHash
+)
+
 func (o *Options) onError(err error) {
 	if o.OnError != nil {
 		o.OnError(err)
@@ -104,8 +304,10 @@ func (o *Options) onError(err error) {
 // When using trace, it is important to call Stop at the end of your program
 // for a clean exit and to flush any remaining tracing data to the Datadog agent.
 // If an error occurs initializing the stats exporter, the error will be returned
-// and the exporter will be nil.
+// and the exporter will be nil. The options which are not set are read from the
+// DD_* environment variables, when available.
 func NewExporter(o Options) (exporter *Exporter, err error) {
+	o = o.withEnv(os.Getenv)
 	statsExporter, err := newStatsExporter(o)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"container/list"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	// defaultDecisionCacheSize specifies the default maximum number of traces
+	// whose sampling decision is remembered.
+	defaultDecisionCacheSize = 10000
+
+	// defaultDecisionCacheTTL specifies the default duration for which the
+	// sampling decision of a trace is remembered.
+	defaultDecisionCacheTTL = time.Minute
+)
+
+// samplingRateKeys holds the keys of the metrics recording the rates which led
+// to a sampling decision. They are only set on the local root span.
+var samplingRateKeys = []string{keySamplingPriorityRate, keyRulePSR, keyLimitPSR}
+
+// samplingDecision holds the sampling decision made for a trace.
+type samplingDecision struct {
+	priority float64
+	rates    map[string]float64 // metrics to set on the local root span
+}
+
+// isUserPriority reports whether p is a user keep or user reject priority.
+func isUserPriority(p float64) bool {
+	return p == ext.PriorityUserKeep || p == ext.PriorityUserReject
+}
+
+// decisionEntry is an entry of a decisionCache.
+type decisionEntry struct {
+	key      traceKey
+	decision samplingDecision
+	expires  time.Time
+}
+
+// decisionCache remembers the sampling decisions made for traces, so that all
+// of their spans get the same one. It holds at most size decisions, evicting
+// the least recently used ones first, and forgets them when unused for ttl. It
+// is not safe for concurrent use.
+type decisionCache struct {
+	size    int
+	ttl     time.Duration
+	entries map[traceKey]*list.Element
+	order   *list.List // of *decisionEntry, least recently used first
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+func newDecisionCache(size int, ttl time.Duration) *decisionCache {
+	return &decisionCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[traceKey]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns the sampling decision made for the given trace, if any, and
+// keeps it for another ttl.
+func (c *decisionCache) get(key traceKey) (samplingDecision, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return samplingDecision{}, false
+	}
+	entry := el.Value.(*decisionEntry)
+	now := c.now()
+	if now.After(entry.expires) {
+		c.remove(el)
+		return samplingDecision{}, false
+	}
+	entry.expires = now.Add(c.ttl)
+	c.order.MoveToBack(el)
+	return entry.decision, true
+}
+
+// put records the sampling decision made for the given trace, evicting expired
+// decisions as well as the least recently used one when the cache is full.
+func (c *decisionCache) put(key traceKey, d samplingDecision) {
+	now := c.now()
+	for el := c.order.Front(); el != nil && now.After(el.Value.(*decisionEntry).expires); el = c.order.Front() {
+		c.remove(el)
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Front())
+	}
+	c.entries[key] = c.order.PushBack(&decisionEntry{
+		key:      key,
+		decision: d,
+		expires:  now.Add(c.ttl),
+	})
+}
+
+// remove removes the given element from the cache.
+func (c *decisionCache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*decisionEntry).key)
+	c.order.Remove(el)
+}
+
+// sampleTrace sets the sampling priority of the given spans, which belong to
+// the same trace, and remembers it for the rest of the trace. Unless it was
+// already made, the decision is made on the given spans: a priority they carry,
+// either set explicitly or propagated from upstream, is kept, preferring user
+// priorities over automatic ones and then the one of the local root. Otherwise
+// the decision is made on the local root span, or else on the first span, using
+// the sampling rules and the agent rates. The retention policy applies to all
+// of the spans. The rates which led to the decision are only recorded on the
+// local root span.
+//
+// A decision which was already made is never changed, as it has been applied
+// to spans which were sent. Hence, unless TailSampling is enabled, a priority
+// carried by a span is ignored when other spans of the trace were sent before.
+func (e *traceExporter) sampleTrace(spans []*Span) {
+	root := spans[0]
+	for _, span := range spans {
+		if span.localRoot {
+			root = span
+			break
+		}
+	}
+	key := traceKey{high: root.traceIDHigh, low: root.TraceID}
+	d, ok := e.decisions.get(key)
+	if !ok {
+		carried := false
+		for _, span := range append([]*Span{root}, spans...) {
+			p, ok := span.Metrics[keySamplingPriority]
+			if ok && (!carried || (isUserPriority(p) && !isUserPriority(d.priority))) {
+				d, carried = samplingDecision{priority: p}, true
+			}
+		}
+		if !carried {
+			d = e.decide(root)
+		}
+		e.retainer.retain(&d, spans...)
+		e.decisions.put(key, d)
+	}
+	for _, span := range spans {
+		span.Metrics[keySamplingPriority] = d.priority
+		if span.localRoot {
+			for k, v := range d.rates {
+				span.Metrics[k] = v
+			}
+		}
+	}
+}
+
+// decide makes the sampling decision for the trace of the given span, which
+// carries no priority, using the sampling rules and the agent rates. The rates
+// which led to the decision are moved from the span to the decision.
+func (e *traceExporter) decide(span *Span) samplingDecision {
+	if !e.rules.apply(span) {
+		e.sampler.applyPriority(span)
+	}
+	d := samplingDecision{priority: span.Metrics[keySamplingPriority]}
+	for _, k := range samplingRateKeys {
+		if v, ok := span.Metrics[k]; ok {
+			if d.rates == nil {
+				d.rates = make(map[string]float64, len(samplingRateKeys))
+			}
+			d.rates[k] = v
+			delete(span.Metrics, k)
+		}
+	}
+	return d
+}
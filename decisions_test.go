@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestDecisionCache(t *testing.T) {
+	now := time.Unix(100, 0)
+	newCache := func() *decisionCache {
+		c := newDecisionCache(2, time.Minute)
+		c.now = func() time.Time { return now }
+		return c
+	}
+	keep := samplingDecision{priority: ext.PriorityAutoKeep}
+	reject := samplingDecision{priority: ext.PriorityAutoReject}
+
+	t.Run("get", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newCache()
+		_, ok := c.get(traceKey{low: 1})
+		eq(ok, false)
+		c.put(traceKey{low: 1}, keep)
+		c.put(traceKey{high: 1, low: 1}, reject)
+		d, ok := c.get(traceKey{low: 1})
+		eq(ok, true)
+		eq(d, keep)
+		d, ok = c.get(traceKey{high: 1, low: 1})
+		eq(ok, true)
+		eq(d, reject)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newCache()
+		c.put(traceKey{low: 1}, keep)
+		c.put(traceKey{low: 2}, keep)
+		c.put(traceKey{low: 3}, keep)
+		_, ok := c.get(traceKey{low: 1})
+		eq(ok, false)
+		_, ok = c.get(traceKey{low: 3})
+		eq(ok, true)
+		eq(len(c.entries), 2)
+		eq(c.order.Len(), 2)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newCache()
+		c.put(traceKey{low: 1}, keep)
+		now = now.Add(time.Minute + 1)
+		_, ok := c.get(traceKey{low: 1})
+		eq(ok, false)
+		eq(len(c.entries), 0)
+
+		c.put(traceKey{low: 2}, keep)
+		now = now.Add(time.Minute + 1)
+		c.put(traceKey{low: 3}, keep)
+		eq(len(c.entries), 1)
+		eq(c.order.Len(), 1)
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newCache()
+		c.put(traceKey{low: 1}, keep)
+		c.put(traceKey{low: 2}, keep)
+		for i := 0; i < 3; i++ {
+			// the trace keeps receiving spans for longer than the ttl
+			now = now.Add(time.Minute / 2)
+			_, ok := c.get(traceKey{low: 1})
+			eq(ok, true)
+		}
+		_, ok := c.get(traceKey{low: 2})
+		eq(ok, false)
+
+		// the least recently used decision is evicted first
+		c.put(traceKey{low: 2}, keep)
+		c.get(traceKey{low: 1})
+		c.put(traceKey{low: 3}, keep)
+		_, ok = c.get(traceKey{low: 1})
+		eq(ok, true)
+		_, ok = c.get(traceKey{low: 2})
+		eq(ok, false)
+	})
+}
+
+func TestConsistentSampling(t *testing.T) {
+	eq := equalFunc(t)
+	e := newTraceExporter(Options{Service: "my-service"})
+	defer e.stop()
+
+	child := e.convertSpan(spanPairs["child"].oc)
+	e.sampleTrace([]*Span{child})
+	eq(child.Metrics[keySamplingPriority], float64(ext.PriorityAutoKeep))
+	_, ok := child.Metrics[keySamplingPriorityRate]
+	eq(ok, false)
+
+	// the rates change mid-trace
+	err := e.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0}}`)))
+	eq(err, nil)
+
+	root := e.convertSpan(spanPairs["root"].oc)
+	e.sampleTrace([]*Span{root})
+	eq(root.Metrics[keySamplingPriority], float64(ext.PriorityAutoKeep))
+	eq(root.Metrics[keySamplingPriorityRate], 1.)
+
+	// a new trace uses the new rates
+	other := *spanPairs["root"].oc
+	other.SpanContext.TraceID[0] = 42
+	span := e.convertSpan(&other)
+	e.sampleTrace([]*Span{span})
+	eq(span.Metrics[keySamplingPriority], float64(ext.PriorityAutoReject))
+	eq(span.Metrics[keySamplingPriorityRate], 0.)
+}
+
+func TestExplicitPriority(t *testing.T) {
+	eq := equalFunc(t)
+	e := newTraceExporter(Options{Service: "my-service"})
+	defer e.stop()
+
+	// the whole trace is sampled at once, as with tail sampling
+	child := e.convertSpan(spanPairs["child"].oc)
+	setTag(child, ext.SamplingPriority, label.Int64Value(ext.PriorityAutoKeep))
+	root := e.convertSpan(spanPairs["root"].oc)
+	setTag(root, ext.SamplingPriority, label.Int64Value(ext.PriorityUserReject))
+	e.sampleTrace([]*Span{child, root})
+	eq(child.Metrics[keySamplingPriority], float64(ext.PriorityUserReject))
+	eq(root.Metrics[keySamplingPriority], float64(ext.PriorityUserReject))
+	_, ok := root.Metrics[keySamplingPriorityRate]
+	eq(ok, false)
+
+	// a decision applied to spans which were sent is never changed
+	other := *spanPairs["child"].oc
+	other.SpanContext.TraceID[0] = 42
+	child = e.convertSpan(&other)
+	e.sampleTrace([]*Span{child})
+	eq(child.Metrics[keySamplingPriority], float64(ext.PriorityAutoKeep))
+
+	other = *spanPairs["root"].oc
+	other.SpanContext.TraceID[0] = 42
+	root = e.convertSpan(&other)
+	setTag(root, ext.SamplingPriority, label.Int64Value(ext.PriorityUserReject))
+	e.sampleTrace([]*Span{root})
+	eq(root.Metrics[keySamplingPriority], float64(ext.PriorityAutoKeep))
+}
@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// environment variables used for unified service tagging and for locating the
+// agent, as supported by the other Datadog libraries.
+const (
+	envService        = "DD_SERVICE"
+	envEnv            = "DD_ENV"
+	envVersion        = "DD_VERSION"
+	envTags           = "DD_TAGS"
+	envAgentHost      = "DD_AGENT_HOST"
+	envTraceAgentPort = "DD_TRACE_AGENT_PORT"
+	envTraceAgentURL  = "DD_TRACE_AGENT_URL"
+	envDogstatsdPort  = "DD_DOGSTATSD_PORT"
+	envDogstatsdURL   = "DD_DOGSTATSD_URL"
+	envSamplingRules  = "DD_TRACE_SAMPLING_RULES"
+	envTraceRateLimit = "DD_TRACE_RATE_LIMIT"
+	envSpanRules      = "DD_SPAN_SAMPLING_RULES"
+)
+
+// defaults used when only some of the agent environment variables are set.
+const (
+	defaultAgentHost     = "localhost"
+	defaultTracePort     = "8126"
+	defaultDogstatsdPort = "8125"
+)
+
+// withEnv returns a copy of o with its unset fields filled in from the DD_*
+// environment variables looked up using getenv. Explicit options always take
+// precedence. The tags found in DD_TAGS are applied to both spans and metrics,
+// before GlobalTags and Tags respectively. The explicit environment and version
+// are added to the metric tags, while the ones found in DD_ENV and DD_VERSION
+// are not, as the statsd client already adds them.
+func (o Options) withEnv(getenv func(string) string) Options {
+	var tags [][2]string
+	if o.Env != "" {
+		tags = append(tags, [2]string{ext.Environment, o.Env})
+	}
+	if o.Version != "" {
+		tags = append(tags, [2]string{keyVersion, o.Version})
+	}
+	if o.Service == "" {
+		o.Service = getenv(envService)
+	}
+	if o.Env == "" {
+		o.Env = getenv(envEnv)
+	}
+	if o.Version == "" {
+		o.Version = getenv(envVersion)
+	}
+	if o.TraceAddr == "" {
+		o.TraceAddr = agentAddr(getenv, envTraceAgentURL, envTraceAgentPort, defaultTracePort)
+	}
+	if o.StatsAddr == "" {
+		o.StatsAddr = agentAddr(getenv, envDogstatsdURL, envDogstatsdPort, defaultDogstatsdPort)
+	}
+	if v := getenv(envSamplingRules); v != "" && len(o.SamplingRules) == 0 {
+		rules, err := parseSamplingRules(v)
+		if err != nil {
+			o.onError(fmt.Errorf("invalid %s: %v", envSamplingRules, err))
+		} else {
+			o.SamplingRules = rules
+		}
+	}
+	if v := getenv(envSpanRules); v != "" && len(o.SpanSamplingRules) == 0 {
+		rules, err := parseSpanSamplingRules(v)
+		if err != nil {
+			o.onError(fmt.Errorf("invalid %s: %v", envSpanRules, err))
+		} else {
+			o.SpanSamplingRules = rules
+		}
+	}
+	if v := getenv(envTraceRateLimit); v != "" && o.TraceRateLimit == 0 {
+		limit, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			o.onError(fmt.Errorf("invalid %s: %v", envTraceRateLimit, err))
+		} else {
+			o.TraceRateLimit = limit
+		}
+	}
+
+	var globalTags []label.KeyValue
+	for _, t := range parseTags(getenv(envTags)) {
+		if (t[0] == ext.Environment && o.Env != "") || (t[0] == keyVersion && o.Version != "") {
+			// DD_ENV, DD_VERSION and the options take precedence
+			continue
+		}
+		globalTags = append(globalTags, label.String(t[0], t[1]))
+		tags = append(tags, t)
+	}
+	if len(globalTags) > 0 {
+		o.GlobalTags = append(globalTags, o.GlobalTags...)
+	}
+	var metricTags []string
+	for _, t := range tags {
+		if !hasTag(o.Tags, t[0]) {
+			metricTags = append(metricTags, t[0]+":"+t[1])
+		}
+	}
+	if len(metricTags) > 0 {
+		o.Tags = append(metricTags, o.Tags...)
+	}
+	return o
+}
+
+// agentAddr returns the host[:port] address of the agent, read from the URL in
+// the urlKey environment variable or else from DD_AGENT_HOST and the portKey
+// environment variable. It returns an empty string when none of them is set,
+// or when the URL does not use the "http", "udp" or "unix" scheme.
+func agentAddr(getenv func(string) string, urlKey, portKey, defaultPort string) string {
+	if v := getenv(urlKey); v != "" {
+		u, err := url.Parse(v)
+		if err != nil {
+			return ""
+		}
+		switch u.Scheme {
+		case "http", "udp":
+			return u.Host
+		case "unix":
+			if portKey == envDogstatsdPort {
+				// only supported by DogStatsD
+				return "unix://" + u.Path
+			}
+		}
+		return ""
+	}
+	host, port := getenv(envAgentHost), getenv(portKey)
+	if host == "" && port == "" {
+		return ""
+	}
+	if host == "" {
+		host = defaultAgentHost
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// parseTags parses the given comma or space separated list of key:value tags,
+// as found in DD_TAGS. Tags without a value are given an empty one.
+func parseTags(str string) [][2]string {
+	var tags [][2]string
+	for _, t := range strings.FieldsFunc(str, func(r rune) bool { return r == ',' || r == ' ' }) {
+		kv := strings.SplitN(t, ":", 2)
+		if kv[0] == "" {
+			continue
+		}
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		tags = append(tags, [2]string{kv[0], kv[1]})
+	}
+	return tags
+}
+
+// hasTag reports whether the given list of key:value tags contains key.
+func hasTag(tags []string, key string) bool {
+	for _, t := range tags {
+		if strings.HasPrefix(t, key+":") || t == key {
+			return true
+		}
+	}
+	return false
+}
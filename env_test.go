@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// testEnv returns a getenv function looking up the given variables.
+func testEnv(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+// clearDDEnv unsets the DD_* environment variables, so that the ones of the
+// host do not leak into the tests, and returns a function restoring them.
+func clearDDEnv() func() {
+	var saved []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "DD_") {
+			saved = append(saved, kv)
+			os.Unsetenv(kv[:strings.IndexByte(kv, '=')])
+		}
+	}
+	return func() {
+		for _, kv := range saved {
+			i := strings.IndexByte(kv, '=')
+			os.Setenv(kv[:i], kv[i+1:])
+		}
+	}
+}
+
+func TestOptionsWithEnv(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		eq := equalFunc(t)
+		o := Options{}.withEnv(testEnv(map[string]string{
+			envService: "my-service",
+			envEnv:     "prod",
+			envVersion: "1.2.3",
+			envTags:    "team:apm, region:us-east-1 flag",
+		}))
+		eq(o.Service, "my-service")
+		eq(o.Env, "prod")
+		eq(o.Version, "1.2.3")
+		eq(o.GlobalTags, []label.KeyValue{
+			label.String("team", "apm"),
+			label.String("region", "us-east-1"),
+			label.String("flag", ""),
+		})
+		// the statsd client adds DD_ENV and DD_VERSION itself
+		eq(o.Tags, []string{"team:apm", "region:us-east-1", "flag:"})
+		eq(o.TraceAddr, "")
+		eq(o.StatsAddr, "")
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		eq := equalFunc(t)
+		o := Options{
+			Service:    "explicit-service",
+			Env:        "staging",
+			TraceAddr:  "agent:1234",
+			Tags:       []string{"team:core"},
+			GlobalTags: []label.KeyValue{label.String("team", "core")},
+		}.withEnv(testEnv(map[string]string{
+			envService:       "my-service",
+			envEnv:           "prod",
+			envTags:          "team:apm,env:dev,version:0.1",
+			envAgentHost:     "dd-agent",
+			envTraceAgentURL: "http://other:8126",
+		}))
+		eq(o.Service, "explicit-service")
+		eq(o.Env, "staging")
+		eq(o.Version, "")
+		eq(o.TraceAddr, "agent:1234")
+		eq(o.StatsAddr, "dd-agent:8125")
+		eq(o.GlobalTags, []label.KeyValue{
+			label.String("team", "apm"),
+			label.String("version", "0.1"),
+			label.String("team", "core"),
+		})
+		eq(o.Tags, []string{"env:staging", "version:0.1", "team:core"})
+	})
+
+	t.Run("sampling-rules", func(t *testing.T) {
+		eq := equalFunc(t)
+		o := Options{}.withEnv(testEnv(map[string]string{
+			envSamplingRules: `[{"service": "payment", "sample_rate": 1}]`,
+		}))
+		eq(o.SamplingRules, []SamplingRule{{Service: "payment", Rate: 1}})
+
+		o = Options{SamplingRules: []SamplingRule{{Rate: 0.5}}}.withEnv(testEnv(map[string]string{
+			envSamplingRules: `[{"service": "payment", "sample_rate": 1}]`,
+		}))
+		eq(o.SamplingRules, []SamplingRule{{Rate: 0.5}})
+
+		var errs []error
+		o = Options{OnError: func(err error) { errs = append(errs, err) }}.withEnv(testEnv(map[string]string{
+			envSamplingRules: `not json`,
+		}))
+		eq(len(o.SamplingRules), 0)
+		eq(len(errs), 1)
+		containsFunc(t)(errs[0], envSamplingRules)
+	})
+
+	t.Run("span-sampling-rules", func(t *testing.T) {
+		eq := equalFunc(t)
+		o := Options{}.withEnv(testEnv(map[string]string{
+			envSpanRules: `[{"service": "billing", "sample_rate": 0.1}]`,
+		}))
+		eq(o.SpanSamplingRules, []SpanSamplingRule{{Service: "billing", Rate: 0.1}})
+	})
+
+	t.Run("rate-limit", func(t *testing.T) {
+		eq := equalFunc(t)
+		o := Options{}.withEnv(testEnv(map[string]string{envTraceRateLimit: "50"}))
+		eq(o.TraceRateLimit, 50.)
+
+		o = Options{TraceRateLimit: 10}.withEnv(testEnv(map[string]string{envTraceRateLimit: "50"}))
+		eq(o.TraceRateLimit, 10.)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		o := Options{Service: "my-service"}.withEnv(testEnv(nil))
+		equalFunc(t)(o, Options{Service: "my-service"})
+	})
+}
+
+func TestConvertSpanEnv(t *testing.T) {
+	eq := equalFunc(t)
+	defer clearDDEnv()()
+	os.Setenv(envEnv, "staging")
+
+	span := ConvertSpan(spanPairs["root"].oc, Options{})
+	_, ok := span.Meta[ext.Environment]
+	eq(ok, false)
+	span = ConvertSpan(spanPairs["root"].oc, Options{Env: "prod"})
+	eq(span.Meta[ext.Environment], "prod")
+}
+
+func TestAgentAddr(t *testing.T) {
+	for name, tt := range map[string]struct {
+		vars  map[string]string
+		trace string
+		stats string
+	}{
+		"none": {},
+		"host": {
+			vars:  map[string]string{envAgentHost: "dd-agent"},
+			trace: "dd-agent:8126",
+			stats: "dd-agent:8125",
+		},
+		"ports": {
+			vars:  map[string]string{envTraceAgentPort: "9126", envDogstatsdPort: "9125"},
+			trace: "localhost:9126",
+			stats: "localhost:9125",
+		},
+		"urls": {
+			vars: map[string]string{
+				envAgentHost:     "ignored",
+				envTraceAgentURL: "http://dd-agent:9126",
+				envDogstatsdURL:  "udp://dd-agent:9125",
+			},
+			trace: "dd-agent:9126",
+			stats: "dd-agent:9125",
+		},
+		"unix": {
+			vars: map[string]string{
+				envTraceAgentURL: "unix:///var/run/datadog/apm.socket",
+				envDogstatsdURL:  "unix:///var/run/datadog/dsd.socket",
+			},
+			stats: "unix:///var/run/datadog/dsd.socket",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			getenv := testEnv(tt.vars)
+			eq(agentAddr(getenv, envTraceAgentURL, envTraceAgentPort, defaultTracePort), tt.trace)
+			eq(agentAddr(getenv, envDogstatsdURL, envDogstatsdPort, defaultDogstatsdPort), tt.stats)
+		})
+	}
+}
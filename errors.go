@@ -38,6 +38,9 @@ const (
 	// to upload spans to the agent.
 	errorTypeTransport
 
+	// errorTypeDropped specifies that a span was dropped by a span processor.
+	errorTypeDropped
+
 	// errorTypeUnknown specifies that an unknown error type was reported.
 	errorTypeUnknown
 )
@@ -47,6 +50,7 @@ var errorTypeStrings = map[errorType]string{
 	errorTypeEncoding:  "encoding error",
 	errorTypeOverflow:  "span buffer overflow",
 	errorTypeTransport: "transport error",
+	errorTypeDropped:   "span dropped by processor",
 	errorTypeUnknown:   "error",
 }
 
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	keyEvents             = "events"
+	keyDroppedEventsCount = "opentelemetry.dropped_events_count"
+
+	// exception event semantic conventions, see:
+	// https://github.com/open-telemetry/opentelemetry-specification/blob/master/specification/trace/semantic_conventions/exceptions.md
+	eventNameException     = "exception"
+	keyExceptionType       = "exception.type"
+	keyExceptionMessage    = "exception.message"
+	keyExceptionStacktrace = "exception.stacktrace"
+)
+
+// ddEvent is the JSON representation of a span event, as stored in the
+// "events" meta entry of a Datadog span.
+type ddEvent struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano int64                  `json:"time_unix_nano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// setEvents encodes the given span events as a JSON array into the "events"
+// meta entry of s, scrubbing their attributes using sc. At most max events are
+// encoded and the resulting value will not exceed maxSize bytes. The number of
+// events which did not make it, including those dropped by the SDK, is recorded
+// as a metric.
+func setEvents(s *Span, events []export.Event, sdkDropped, max, maxSize int, sc *scrubber) {
+	if max < 0 {
+		return
+	}
+	dropped := sdkDropped
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	n := 0
+	for _, ev := range events {
+		if n >= max {
+			dropped++
+			continue
+		}
+		b, err := json.Marshal(newDDEvent(ev, sc))
+		if err != nil || buf.Len()+len(b)+2 > maxSize {
+			// account for the separator and the closing bracket
+			dropped++
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+		n++
+	}
+	buf.WriteByte(']')
+	if n > 0 {
+		s.Meta[keyEvents] = buf.String()
+	}
+	if dropped > 0 {
+		s.Metrics[keyDroppedEventsCount] = float64(dropped)
+	}
+}
+
+// newDDEvent converts an OpenTelemetry span event to a ddEvent, scrubbing its
+// attributes using sc.
+func newDDEvent(ev export.Event, sc *scrubber) ddEvent {
+	dde := ddEvent{
+		Name:         ev.Name,
+		TimeUnixNano: ev.Time.UnixNano(),
+	}
+	if len(ev.Attributes) > 0 {
+		dde.Attributes = make(map[string]interface{}, len(ev.Attributes))
+		for _, attr := range ev.Attributes {
+			dde.Attributes[string(attr.Key)] = attributeValue(sc.scrub(string(attr.Key), attr.Value))
+		}
+	}
+	return dde
+}
+
+// attributeValue returns the JSON-encodable value of v.
+func attributeValue(v label.Value) interface{} {
+	if v.Type() == label.INVALID {
+		return nil
+	}
+	return v.AsInterface()
+}
+
+// hasException reports whether events contain an exception event.
+func hasException(events []export.Event) bool {
+	for _, ev := range events {
+		if ev.Name == eventNameException {
+			return true
+		}
+	}
+	return false
+}
+
+// setExceptionError sets the error details of s using the attributes of the
+// last exception event found in events, if any. The values it finds take
+// precedence over the error details derived from the span status. Whether s is
+// an error is left to the ErrorClassifier and the "error" attribute.
+func setExceptionError(s *Span, events []export.Event) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Name != eventNameException {
+			continue
+		}
+		for _, attr := range events[i].Attributes {
+			if attr.Value.Type() != label.STRING {
+				continue
+			}
+			switch attr.Key {
+			case keyExceptionType:
+				s.Meta[ext.ErrorType] = attr.Value.AsString()
+			case keyExceptionMessage:
+				s.Meta[ext.ErrorMsg] = attr.Value.AsString()
+			case keyExceptionStacktrace:
+				s.Meta[ext.ErrorStack] = attr.Value.AsString()
+			}
+		}
+		return
+	}
+}
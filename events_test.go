@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestSetEvents(t *testing.T) {
+	testSpan := func() *Span {
+		return &Span{
+			Meta:    map[string]string{},
+			Metrics: map[string]float64{},
+		}
+	}
+	evTime := time.Unix(1, 2)
+	events := []export.Event{
+		{
+			Name: "retry",
+			Time: evTime,
+			Attributes: []label.KeyValue{
+				label.String("str", "abc"),
+				label.Bool("bool", true),
+				label.Int64("int64", 2),
+				label.Array("array", []string{"a", "b"}),
+			},
+		},
+		{Name: "cache.miss", Time: evTime},
+	}
+
+	t.Run("encode", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setEvents(span, events, 0, defaultMaxEvents, defaultMaxEventsSize, nil)
+		eq(span.Meta[keyEvents], `[{"name":"retry","time_unix_nano":1000000002,"attributes":{"array":["a","b"],"bool":true,"int64":2,"str":"abc"}},{"name":"cache.miss","time_unix_nano":1000000002}]`)
+		_, ok := span.Metrics[keyDroppedEventsCount]
+		eq(ok, false)
+
+		var got []ddEvent
+		if err := json.Unmarshal([]byte(span.Meta[keyEvents]), &got); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(got), 2)
+		eq(got[1].Name, "cache.miss")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		span := testSpan()
+		setEvents(span, nil, 0, defaultMaxEvents, defaultMaxEventsSize, nil)
+		equalFunc(t)(len(span.Meta), 0)
+	})
+
+	t.Run("max", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setEvents(span, events, 3, 1, defaultMaxEventsSize, nil)
+		eq(strings.Count(span.Meta[keyEvents], `"name"`), 1)
+		eq(span.Metrics[keyDroppedEventsCount], 4.)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setEvents(span, events, 0, defaultMaxEvents, 64, nil)
+		eq(span.Meta[keyEvents], `[{"name":"cache.miss","time_unix_nano":1000000002}]`)
+		eq(span.Metrics[keyDroppedEventsCount], 1.)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		span := testSpan()
+		setEvents(span, events, 0, -1, defaultMaxEventsSize, nil)
+		equalFunc(t)(len(span.Meta), 0)
+	})
+}
+
+func TestSetExceptionError(t *testing.T) {
+	e := newTraceExporter(Options{Service: "my-service"})
+	defer e.stop()
+
+	t.Run("exception", func(t *testing.T) {
+		eq := equalFunc(t)
+		sd := *spanPairs["root"].oc
+		sd.MessageEvents = []export.Event{
+			{
+				Name: eventNameException,
+				Attributes: []label.KeyValue{
+					label.String(keyExceptionType, "*errors.errorString"),
+					label.String(keyExceptionMessage, "boom"),
+					label.String(keyExceptionStacktrace, "main.main()"),
+				},
+			},
+		}
+		span := e.convertSpan(&sd)
+		eq(span.Error, int32(1))
+		eq(span.Meta[ext.ErrorType], "*errors.errorString")
+		eq(span.Meta[ext.ErrorMsg], "boom")
+		eq(span.Meta[ext.ErrorStack], "main.main()")
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		eq := equalFunc(t)
+		sd := *spanPairs["server_error_5xx"].oc
+		sd.MessageEvents = []export.Event{
+			{
+				Name:       eventNameException,
+				Attributes: []label.KeyValue{label.String(keyExceptionStacktrace, "main.main()")},
+			},
+		}
+		span := e.convertSpan(&sd)
+		eq(span.Error, int32(1))
+		eq(span.Meta[ext.ErrorType], "INTERNAL")
+		eq(span.Meta[ext.ErrorMsg], "status-msg")
+		eq(span.Meta[ext.ErrorStack], "main.main()")
+	})
+
+	t.Run("not-an-error", func(t *testing.T) {
+		eq := equalFunc(t)
+		sd := *spanPairs["root"].oc
+		sd.MessageEvents = []export.Event{
+			{
+				Name:       eventNameException,
+				Attributes: []label.KeyValue{label.String(keyExceptionStacktrace, "main.main()")},
+			},
+		}
+		c := newSpanConverter(Options{
+			ErrorClassifier: func(*export.SpanData) (bool, string, string) { return false, "", "" },
+		})
+		span := c.convertSpan(&sd)
+		eq(span.Error, int32(0))
+		eq(span.Meta[ext.ErrorStack], "main.main()")
+
+		sd.Attributes = []label.KeyValue{label.Bool(ext.Error, false)}
+		span = e.convertSpan(&sd)
+		eq(span.Error, int32(0))
+		eq(span.Meta[ext.ErrorStack], "main.main()")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		sd := *spanPairs["root"].oc
+		sd.MessageEvents = []export.Event{{Name: "retry"}}
+		span := e.convertSpan(&sd)
+		equalFunc(t)(span.Error, int32(0))
+	})
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// default span limits, matching the ones enforced by the agent.
+const (
+	defaultMaxTagKeyLength   = 200
+	defaultMaxTagValueLength = 25000
+	defaultMaxMetaEntries    = 1000
+	defaultMaxResourceLength = 5000
+	defaultMaxServiceLength  = 100
+)
+
+const (
+	// truncationMarker is appended to truncated values.
+	truncationMarker = "..."
+
+	keyTruncatedCount   = "opentelemetry.truncated_count"
+	keyDroppedTagsCount = "opentelemetry.dropped_tags_count"
+)
+
+// spanLimits holds the size limits enforced on converted spans. A negative
+// limit disables the corresponding check.
+type spanLimits struct {
+	keyLength      int
+	valueLength    int
+	metaEntries    int
+	resourceLength int
+	serviceLength  int
+}
+
+// apply enforces the limits on s. Oversize values are truncated and marked,
+// except for the JSON-encoded events and links, which are bounded when encoded.
+// Oversize keys are truncated in order and their entries are dropped when the
+// truncated key is already taken. The meta entries exceeding the maximum count
+// are dropped in reverse key order, sparing the reserved ones. The number of
+// truncated values and dropped entries are recorded as metrics.
+func (l spanLimits) apply(s *Span) {
+	truncated, dropped := 0, 0
+	var ok bool
+	if s.Service, ok = truncate(s.Service, l.serviceLength); ok {
+		truncated++
+	}
+	if s.Resource, ok = truncate(s.Resource, l.resourceLength); ok {
+		truncated++
+	}
+	var long []string
+	for k, v := range s.Meta {
+		// truncating the JSON-encoded entries would make them invalid
+		isJSON := k == keyEvents || k == keySpanLinks
+		if val, ok := truncate(v, l.valueLength); ok && !isJSON {
+			s.Meta[k] = val
+			truncated++
+		}
+		if _, ok := truncate(k, l.keyLength); ok {
+			long = append(long, k)
+		}
+	}
+	sort.Strings(long)
+	for _, k := range long {
+		key, _ := truncate(k, l.keyLength)
+		v := s.Meta[k]
+		delete(s.Meta, k)
+		if _, ok := s.Meta[key]; ok {
+			dropped++
+			continue
+		}
+		s.Meta[key] = v
+		truncated++
+	}
+	long = long[:0]
+	for k := range s.Metrics {
+		if _, ok := truncate(k, l.keyLength); ok {
+			long = append(long, k)
+		}
+	}
+	sort.Strings(long)
+	for _, k := range long {
+		key, _ := truncate(k, l.keyLength)
+		v := s.Metrics[k]
+		delete(s.Metrics, k)
+		if _, ok := s.Metrics[key]; ok {
+			dropped++
+			continue
+		}
+		s.Metrics[key] = v
+		truncated++
+	}
+	if l.metaEntries >= 0 && len(s.Meta) > l.metaEntries {
+		keys := make([]string, 0, len(s.Meta))
+		for k := range s.Meta {
+			if !isReservedTag(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		n := len(s.Meta) - l.metaEntries
+		if n > len(keys) {
+			n = len(keys)
+		}
+		for _, k := range keys[len(keys)-n:] {
+			delete(s.Meta, k)
+		}
+		dropped += n
+	}
+	if truncated > 0 {
+		s.Metrics[keyTruncatedCount] = float64(truncated)
+	}
+	if dropped > 0 {
+		s.Metrics[keyDroppedTagsCount] = float64(dropped)
+	}
+}
+
+// isReservedTag reports whether key is reserved by Datadog, in which case its
+// entry is never dropped to enforce the maximum number of meta entries.
+func isReservedTag(key string) bool {
+	return key == ext.Environment || key == keyVersion ||
+		strings.HasPrefix(key, "error.") || strings.HasPrefix(key, "_dd.")
+}
+
+// truncate shortens str to max bytes, including the truncation marker, without
+// splitting multi-byte characters. It reports whether str was truncated.
+func truncate(str string, max int) (string, bool) {
+	if max < 0 || len(str) <= max {
+		return str, false
+	}
+	n := max - len(truncationMarker)
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(str[n]) {
+		n--
+	}
+	return str[:n] + truncationMarker, true
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		in        string
+		max       int
+		out       string
+		truncated bool
+	}{
+		{in: "abcdef", max: 6, out: "abcdef"},
+		{in: "abcdef", max: 5, out: "ab...", truncated: true},
+		{in: "abcdef", max: -1, out: "abcdef"},
+		{in: "abcdef", max: 2, out: "...", truncated: true},
+		{in: "aéééé", max: 5, out: "a...", truncated: true},
+	} {
+		out, truncated := truncate(tt.in, tt.max)
+		eq := equalFunc(t)
+		eq(out, tt.out)
+		eq(truncated, tt.truncated)
+	}
+}
+
+func TestSpanLimits(t *testing.T) {
+	t.Run("truncate", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := &Span{
+			Service:  "my-long-service",
+			Resource: "SELECT * FROM users",
+			Meta:     map[string]string{"short": "value", "long.key": "v", "k": "long value"},
+			Metrics:  map[string]float64{"long.metric": 1, "m": 2},
+		}
+		spanLimits{
+			keyLength:      5,
+			valueLength:    8,
+			metaEntries:    -1,
+			resourceLength: 10,
+			serviceLength:  8,
+		}.apply(span)
+		eq(span.Service, "my-lo...")
+		eq(span.Resource, "SELECT ...")
+		eq(span.Meta, map[string]string{"short": "value", "lo...": "v", "k": "long ..."})
+		eq(span.Metrics, map[string]float64{"lo...": 1, "m": 2, keyTruncatedCount: 5})
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := &Span{
+			Meta:    map[string]string{"c": "3", "a": "1", "b": "2", "d": "4"},
+			Metrics: map[string]float64{},
+		}
+		spanLimits{keyLength: -1, valueLength: -1, metaEntries: 2, resourceLength: -1, serviceLength: -1}.apply(span)
+		eq(span.Meta, map[string]string{"a": "1", "b": "2"})
+		eq(span.Metrics, map[string]float64{keyDroppedTagsCount: 2})
+	})
+
+	t.Run("json", func(t *testing.T) {
+		eq := equalFunc(t)
+		events := `[{"name":"event","time_unix_nano":1}]`
+		links := `[{"trace_id":1,"span_id":2}]`
+		span := &Span{
+			Meta:    map[string]string{keyEvents: events, keySpanLinks: links, "k": "long value"},
+			Metrics: map[string]float64{},
+		}
+		spanLimits{keyLength: -1, valueLength: 8, metaEntries: -1, resourceLength: -1, serviceLength: -1}.apply(span)
+		eq(span.Meta, map[string]string{keyEvents: events, keySpanLinks: links, "k": "long ..."})
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := &Span{
+			Meta: map[string]string{
+				"a":             "1",
+				"z":             "2",
+				ext.Environment: "prod",
+				ext.ErrorMsg:    "boom",
+				keySpanLinks:    "[]",
+				keyVersion:      "1.0",
+				"y":             "3",
+			},
+			Metrics: map[string]float64{},
+		}
+		spanLimits{keyLength: -1, valueLength: -1, metaEntries: 5, resourceLength: -1, serviceLength: -1}.apply(span)
+		eq(span.Meta, map[string]string{
+			"a":             "1",
+			ext.Environment: "prod",
+			ext.ErrorMsg:    "boom",
+			keySpanLinks:    "[]",
+			keyVersion:      "1.0",
+		})
+		eq(span.Metrics, map[string]float64{keyDroppedTagsCount: 2})
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := &Span{
+			Meta:    map[string]string{"long.a": "1", "long.b": "2", "lo...": "3"},
+			Metrics: map[string]float64{"metric.a": 1, "metric.b": 2},
+		}
+		spanLimits{keyLength: 5, valueLength: -1, metaEntries: -1, resourceLength: -1, serviceLength: -1}.apply(span)
+		eq(span.Meta, map[string]string{"lo...": "3"})
+		eq(span.Metrics, map[string]float64{"me...": 1, keyTruncatedCount: 1, keyDroppedTagsCount: 3})
+	})
+
+	t.Run("convert", func(t *testing.T) {
+		eq := equalFunc(t)
+		sd := *spanPairs["root"].oc
+		sd.Attributes = []label.KeyValue{label.String("big", strings.Repeat("x", 30000))}
+		span := ConvertSpan(&sd, Options{})
+		eq(len(span.Meta["big"]), defaultMaxTagValueLength)
+		eq(strings.HasSuffix(span.Meta["big"], truncationMarker), true)
+		eq(span.Metrics[keyTruncatedCount], 1.)
+
+		span = ConvertSpan(&sd, Options{MaxTagValueLength: -1})
+		eq(len(span.Meta["big"]), 30000)
+	})
+}
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+const (
+	keySpanLinks         = "_dd.span_links"
+	keyDroppedLinksCount = "opentelemetry.dropped_links_count"
+
+	// linkFlagsSet is set on the flags of an encoded link to tell apart
+	// empty trace flags from missing ones.
+	linkFlagsSet = uint32(1) << 31
+)
+
+// ddSpanLink is the JSON representation of a span link, as stored in the
+// "_dd.span_links" meta entry of a Datadog span.
+type ddSpanLink struct {
+	TraceID     uint64            `json:"trace_id"`
+	TraceIDHigh uint64            `json:"trace_id_high,omitempty"`
+	SpanID      uint64            `json:"span_id"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Flags       uint32            `json:"flags,omitempty"`
+}
+
+// setLinks encodes the given span links as a JSON array into the
+// "_dd.span_links" meta entry of s, mapping their trace IDs using traceID and
+// scrubbing their attributes using sc. The resulting value will not exceed
+// maxSize bytes. The number of links which did not make it, including those
+// dropped by the SDK, is recorded as a metric.
+func setLinks(s *Span, links []trace.Link, sdkDropped, maxSize int, traceID func(trace.ID) (low, high uint64), sc *scrubber) {
+	dropped := sdkDropped
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	n := 0
+	for _, l := range links {
+		b, err := json.Marshal(newDDSpanLink(l, traceID, sc))
+		if err != nil || buf.Len()+len(b)+2 > maxSize {
+			// account for the separator and the closing bracket
+			dropped++
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+		n++
+	}
+	buf.WriteByte(']')
+	if n > 0 {
+		s.Meta[keySpanLinks] = buf.String()
+	}
+	if dropped > 0 {
+		s.Metrics[keyDroppedLinksCount] = float64(dropped)
+	}
+}
+
+// newDDSpanLink converts an OpenTelemetry span link to a ddSpanLink, mapping
+// its trace ID using traceID, the same way as the one of the linking span, and
+// scrubbing its attributes using sc.
+func newDDSpanLink(l trace.Link, traceID func(trace.ID) (low, high uint64), sc *scrubber) ddSpanLink {
+	ddl := ddSpanLink{
+		SpanID: binary.BigEndian.Uint64(l.SpanID[:]),
+		Flags:  uint32(l.TraceFlags) | linkFlagsSet,
+	}
+	ddl.TraceID, ddl.TraceIDHigh = traceID(l.TraceID)
+	if len(l.Attributes) > 0 {
+		ddl.Attributes = make(map[string]string, len(l.Attributes))
+		for _, attr := range l.Attributes {
+			ddl.Attributes[string(attr.Key)] = sc.scrub(string(attr.Key), attr.Value).Emit()
+		}
+	}
+	return ddl
+}
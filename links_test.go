@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tinylib/msgp/msgp"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+)
+
+func TestSetLinks(t *testing.T) {
+	testSpan := func() *Span {
+		return &Span{
+			Meta:    map[string]string{},
+			Metrics: map[string]float64{},
+		}
+	}
+	links := []trace.Link{
+		{
+			SpanContext: trace.SpanContext{
+				TraceID:    trace.ID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}),
+				SpanID:     trace.SpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+				TraceFlags: 1,
+			},
+			Attributes: []label.KeyValue{
+				label.String("str", "abc"),
+				label.Int64("int64", 1),
+			},
+		},
+		{
+			SpanContext: trace.SpanContext{
+				TraceID: trace.ID([16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}),
+				SpanID:  trace.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 3}),
+			},
+		},
+	}
+
+	traceID := newSpanConverter(Options{}).traceID
+
+	t.Run("encode", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setLinks(span, links, 0, defaultMaxLinksSize, traceID, nil)
+		eq(span.Meta[keySpanLinks], `[{"trace_id":651345242494996240,"trace_id_high":72623859790382856,"span_id":72623859790382856,"attributes":{"int64":"1","str":"abc"},"flags":2147483649},{"trace_id":2,"span_id":3,"flags":2147483648}]`)
+		_, ok := span.Metrics[keyDroppedLinksCount]
+		eq(ok, false)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		span := testSpan()
+		setLinks(span, nil, 0, defaultMaxLinksSize, traceID, nil)
+		equalFunc(t)(len(span.Meta), 0)
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setLinks(span, links[:1], 3, defaultMaxLinksSize, traceID, nil)
+		eq(span.Metrics[keyDroppedLinksCount], 3.)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := testSpan()
+		setLinks(span, links, 1, 64, traceID, nil)
+		eq(span.Meta[keySpanLinks], `[{"trace_id":2,"span_id":3,"flags":2147483648}]`)
+		eq(span.Metrics[keyDroppedLinksCount], 2.)
+
+		span = testSpan()
+		setLinks(span, links, 0, 16, traceID, nil)
+		_, ok := span.Meta[keySpanLinks]
+		eq(ok, false)
+		eq(span.Metrics[keyDroppedLinksCount], 2.)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newSpanConverter(Options{TraceIDMapping: TraceIDHash})
+		sd := *spanPairs["root"].oc
+		sd.Links = []trace.Link{{SpanContext: sd.SpanContext}}
+		span := c.convertSpan(&sd)
+
+		var ddLinks []ddSpanLink
+		if err := json.Unmarshal([]byte(span.Meta[keySpanLinks]), &ddLinks); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(ddLinks), 1)
+		eq(ddLinks[0].TraceID, span.TraceID)
+		eq(ddLinks[0].TraceIDHigh, span.traceIDHigh)
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		sd := *spanPairs["root"].oc
+		sd.Links = links
+		sd.DroppedLinkCount = 1
+
+		p := newPayload()
+		if err := p.add(e.convertSpan(&sd)); err != nil {
+			t.Fatal(err)
+		}
+		var got ddPayload
+		if err := msgp.Decode(p.buffer(), &got); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(got), 1)
+		eq(len(got[0]), 1)
+		span := got[0][0]
+		eq(span.Metrics[keyDroppedLinksCount], 1.)
+
+		var ddLinks []ddSpanLink
+		if err := json.Unmarshal([]byte(span.Meta[keySpanLinks]), &ddLinks); err != nil {
+			t.Fatal(err)
+		}
+		eq(ddLinks, []ddSpanLink{
+			{
+				TraceID:     span.TraceID,
+				TraceIDHigh: 72623859790382856,
+				SpanID:      72623859790382856,
+				Attributes:  map[string]string{"str": "abc", "int64": "1"},
+				Flags:       linkFlagsSet | 1,
+			},
+			{
+				TraceID: 2,
+				SpanID:  3,
+				Flags:   linkFlagsSet,
+			},
+		})
+	})
+}
@@ -12,30 +12,49 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 
 	"github.com/tinylib/msgp/msgp"
 )
 
-type (
-	ddPayload []ddTrace // used in tests
-	ddTrace   []ddSpan  // used in tests
-)
-
-// ddSpan represents the Datadog span definition.
-type ddSpan struct {
-	SpanID   uint64             `msg:"span_id"`
-	TraceID  uint64             `msg:"trace_id"`
-	ParentID uint64             `msg:"parent_id"`
-	Name     string             `msg:"name"`
-	Service  string             `msg:"service"`
-	Resource string             `msg:"resource"`
-	Type     string             `msg:"type"`
-	Start    int64              `msg:"start"`
-	Duration int64              `msg:"duration"`
+// ddPayload is the list of traces making up a payload sent to the agent.
+type ddPayload []Trace
+
+// Trace is a list of Datadog spans sharing the same trace ID.
+type Trace []Span
+
+// Span represents a span as sent to the Datadog agent.
+type Span struct {
+	SpanID   uint64             `msg:"span_id"`   // identifier of this span
+	TraceID  uint64             `msg:"trace_id"`  // identifier of the trace
+	ParentID uint64             `msg:"parent_id"` // identifier of the parent span, zero for roots
+	Name     string             `msg:"name"`      // operation name
+	Service  string             `msg:"service"`   // service name
+	Resource string             `msg:"resource"`  // resource name
+	Type     string             `msg:"type"`      // span type, e.g. "web" or "sql"
+	Start    int64              `msg:"start"`     // start time in nanoseconds since the epoch
+	Duration int64              `msg:"duration"`  // duration in nanoseconds
 	Meta     map[string]string  `msg:"meta,omitempty"`
 	Metrics  map[string]float64 `msg:"metrics,omitempty"`
-	Error    int32              `msg:"error"`
+	Error    int32              `msg:"error"` // 1 if the span is an error
+
+	// traceIDHigh holds the high 64 bits of the 128-bit trace ID. It is
+	// not encoded, the "_dd.p.tid" tag carries it to the agent.
+	traceIDHigh uint64
+
+	// localRoot is true when the span has no parent or a remote one.
+	localRoot bool
+}
+
+// DecodePayload decodes a msgpack-encoded payload, as sent to the Datadog
+// agent by the exporter, into the traces it holds.
+func DecodePayload(r io.Reader) ([]Trace, error) {
+	var p ddPayload
+	if err := msgp.Decode(r, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 // maxLength indicates the maximum number of items supported in a msgpack-encoded array.
@@ -49,20 +68,25 @@ var errOverflow = fmt.Errorf("maximum msgpack array length (%d) exceeded", maxLe
 // It allows adding spans sequentially while keeping track of the size of the resulting payload.
 type payload struct {
 	// traces maps trace IDs to their specific set of msgpack-encoded spans.
-	traces map[uint64]*packedSpans
+	traces map[traceKey]*packedSpans
 
 	// headerlessSize specifies the size of the payload in bytes, excluding the header
 	// which can range between 1 to 5 bytes, depending on len(traces).
 	headerlessSize int
 }
 
+// traceKey identifies a trace by its full 128-bit ID.
+type traceKey struct {
+	high, low uint64
+}
+
 func newPayload() *payload {
-	return &payload{traces: make(map[uint64]*packedSpans)}
+	return &payload{traces: make(map[traceKey]*packedSpans)}
 }
 
 // reset resets the payload, making it ready to use for a new buffer.
 func (p *payload) reset() {
-	p.traces = make(map[uint64]*packedSpans)
+	p.traces = make(map[traceKey]*packedSpans)
 	p.headerlessSize = 0
 }
 
@@ -73,11 +97,11 @@ func (p *payload) size() int {
 }
 
 // add adds the given span to the payload.
-func (p *payload) add(span *ddSpan) error {
+func (p *payload) add(span *Span) error {
 	if uint(len(p.traces)) >= maxLength {
 		return errOverflow
 	}
-	id := span.TraceID
+	id := traceKey{high: span.traceIDHigh, low: span.TraceID}
 	if _, ok := p.traces[id]; !ok {
 		p.traces[id] = new(packedSpans)
 	}
@@ -112,7 +136,7 @@ type packedSpans struct {
 }
 
 // add adds the given span to the trace.
-func (s *packedSpans) add(span *ddSpan) error {
+func (s *packedSpans) add(span *Span) error {
 	if uint(s.count) >= maxLength {
 		return errOverflow
 	}
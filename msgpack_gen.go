@@ -9,73 +9,7 @@ import (
 )
 
 // DecodeMsg implements msgp.Decodable
-func (z *ddPayload) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0003 uint32
-	zb0003, err = dc.ReadArrayHeader()
-	if err != nil {
-		return
-	}
-	if cap((*z)) >= int(zb0003) {
-		(*z) = (*z)[:zb0003]
-	} else {
-		(*z) = make(ddPayload, zb0003)
-	}
-	for zb0001 := range *z {
-		var zb0004 uint32
-		zb0004, err = dc.ReadArrayHeader()
-		if err != nil {
-			return
-		}
-		if cap((*z)[zb0001]) >= int(zb0004) {
-			(*z)[zb0001] = ((*z)[zb0001])[:zb0004]
-		} else {
-			(*z)[zb0001] = make(ddTrace, zb0004)
-		}
-		for zb0002 := range (*z)[zb0001] {
-			err = (*z)[zb0001][zb0002].DecodeMsg(dc)
-			if err != nil {
-				return
-			}
-		}
-	}
-	return
-}
-
-// EncodeMsg implements msgp.Encodable
-func (z ddPayload) EncodeMsg(en *msgp.Writer) (err error) {
-	err = en.WriteArrayHeader(uint32(len(z)))
-	if err != nil {
-		return
-	}
-	for zb0005 := range z {
-		err = en.WriteArrayHeader(uint32(len(z[zb0005])))
-		if err != nil {
-			return
-		}
-		for zb0006 := range z[zb0005] {
-			err = z[zb0005][zb0006].EncodeMsg(en)
-			if err != nil {
-				return
-			}
-		}
-	}
-	return
-}
-
-// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z ddPayload) Msgsize() (s int) {
-	s = msgp.ArrayHeaderSize
-	for zb0005 := range z {
-		s += msgp.ArrayHeaderSize
-		for zb0006 := range z[zb0005] {
-			s += z[zb0005][zb0006].Msgsize()
-		}
-	}
-	return
-}
-
-// DecodeMsg implements msgp.Decodable
-func (z *ddSpan) DecodeMsg(dc *msgp.Reader) (err error) {
+func (z *Span) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
 	_ = field
 	var zb0001 uint32
@@ -205,7 +139,7 @@ func (z *ddSpan) DecodeMsg(dc *msgp.Reader) (err error) {
 }
 
 // EncodeMsg implements msgp.Encodable
-func (z *ddSpan) EncodeMsg(en *msgp.Writer) (err error) {
+func (z *Span) EncodeMsg(en *msgp.Writer) (err error) {
 	// map header, size 12
 	// write "span_id"
 	err = en.Append(0x8c, 0xa7, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x69, 0x64)
@@ -339,7 +273,7 @@ func (z *ddSpan) EncodeMsg(en *msgp.Writer) (err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z *ddSpan) Msgsize() (s int) {
+func (z *Span) Msgsize() (s int) {
 	s = 1 + 8 + msgp.Uint64Size + 9 + msgp.Uint64Size + 10 + msgp.Uint64Size + 5 + msgp.StringPrefixSize + len(z.Name) + 8 + msgp.StringPrefixSize + len(z.Service) + 9 + msgp.StringPrefixSize + len(z.Resource) + 5 + msgp.StringPrefixSize + len(z.Type) + 6 + msgp.Int64Size + 9 + msgp.Int64Size + 5 + msgp.MapHeaderSize
 	if z.Meta != nil {
 		for za0001, za0002 := range z.Meta {
@@ -359,7 +293,7 @@ func (z *ddSpan) Msgsize() (s int) {
 }
 
 // DecodeMsg implements msgp.Decodable
-func (z *ddTrace) DecodeMsg(dc *msgp.Reader) (err error) {
+func (z *Trace) DecodeMsg(dc *msgp.Reader) (err error) {
 	var zb0002 uint32
 	zb0002, err = dc.ReadArrayHeader()
 	if err != nil {
@@ -368,7 +302,7 @@ func (z *ddTrace) DecodeMsg(dc *msgp.Reader) (err error) {
 	if cap((*z)) >= int(zb0002) {
 		(*z) = (*z)[:zb0002]
 	} else {
-		(*z) = make(ddTrace, zb0002)
+		(*z) = make(Trace, zb0002)
 	}
 	for zb0001 := range *z {
 		err = (*z)[zb0001].DecodeMsg(dc)
@@ -380,7 +314,7 @@ func (z *ddTrace) DecodeMsg(dc *msgp.Reader) (err error) {
 }
 
 // EncodeMsg implements msgp.Encodable
-func (z ddTrace) EncodeMsg(en *msgp.Writer) (err error) {
+func (z Trace) EncodeMsg(en *msgp.Writer) (err error) {
 	err = en.WriteArrayHeader(uint32(len(z)))
 	if err != nil {
 		return
@@ -395,10 +329,76 @@ func (z ddTrace) EncodeMsg(en *msgp.Writer) (err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z ddTrace) Msgsize() (s int) {
+func (z Trace) Msgsize() (s int) {
 	s = msgp.ArrayHeaderSize
 	for zb0003 := range z {
 		s += z[zb0003].Msgsize()
 	}
 	return
 }
+
+// DecodeMsg implements msgp.Decodable
+func (z *ddPayload) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0003 uint32
+	zb0003, err = dc.ReadArrayHeader()
+	if err != nil {
+		return
+	}
+	if cap((*z)) >= int(zb0003) {
+		(*z) = (*z)[:zb0003]
+	} else {
+		(*z) = make(ddPayload, zb0003)
+	}
+	for zb0001 := range *z {
+		var zb0004 uint32
+		zb0004, err = dc.ReadArrayHeader()
+		if err != nil {
+			return
+		}
+		if cap((*z)[zb0001]) >= int(zb0004) {
+			(*z)[zb0001] = ((*z)[zb0001])[:zb0004]
+		} else {
+			(*z)[zb0001] = make(Trace, zb0004)
+		}
+		for zb0002 := range (*z)[zb0001] {
+			err = (*z)[zb0001][zb0002].DecodeMsg(dc)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z ddPayload) EncodeMsg(en *msgp.Writer) (err error) {
+	err = en.WriteArrayHeader(uint32(len(z)))
+	if err != nil {
+		return
+	}
+	for zb0005 := range z {
+		err = en.WriteArrayHeader(uint32(len(z[zb0005])))
+		if err != nil {
+			return
+		}
+		for zb0006 := range z[zb0005] {
+			err = z[zb0005][zb0006].EncodeMsg(en)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z ddPayload) Msgsize() (s int) {
+	s = msgp.ArrayHeaderSize
+	for zb0005 := range z {
+		s += msgp.ArrayHeaderSize
+		for zb0006 := range z[zb0005] {
+			s += z[zb0005][zb0006].Msgsize()
+		}
+	}
+	return
+}
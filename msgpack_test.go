@@ -20,7 +20,7 @@ func TestPayload(t *testing.T) {
 		p := newPayload()
 		prevSize := 0
 		for i, tt := range []struct {
-			span         *ddSpan           // span to add
+			span         *Span             // span to add
 			traceLengths map[uint64]uint64 // maps traces to their expected length
 		}{
 			{
@@ -53,7 +53,7 @@ func TestPayload(t *testing.T) {
 				t.Fatalf("%d: %v", i, err)
 			}
 			for id, total := range tt.traceLengths {
-				if got := p.traces[id].count; got != total {
+				if got := p.traces[traceKey{low: id}].count; got != total {
 					t.Fatalf("%d: count mismatch at trace ID %d, expected %d, got %d", i, id, total, got)
 				}
 			}
@@ -64,6 +64,21 @@ func TestPayload(t *testing.T) {
 		}
 	})
 
+	t.Run("128-bit", func(t *testing.T) {
+		p := newPayload()
+		for _, high := range []uint64{0, 1, 1, 2} {
+			span := makeSpan(100)
+			span.traceIDHigh = high
+			if err := p.add(span); err != nil {
+				t.Fatal(err)
+			}
+		}
+		eq := equalFunc(t)
+		eq(len(p.traces), 3)
+		eq(p.traces[traceKey{high: 1, low: 100}].count, uint64(2))
+		eq(p.traces[traceKey{high: 2, low: 100}].count, uint64(1))
+	})
+
 	t.Run("size", func(t *testing.T) {
 		p := newPayload()
 		if p.size() != 0 {
@@ -108,6 +123,31 @@ func TestPayload(t *testing.T) {
 	})
 }
 
+func TestDecodePayload(t *testing.T) {
+	eq := equalFunc(t)
+	p := newPayload()
+	span := ConvertSpan(spanPairs["root"].oc, Options{Service: "my-service"})
+	if err := p.add(span); err != nil {
+		t.Fatal(err)
+	}
+	traces, err := DecodePayload(p.buffer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(len(traces), 1)
+	eq(len(traces[0]), 1)
+	got := traces[0][0]
+	eq(got.TraceID, span.TraceID)
+	eq(got.Resource, span.Resource)
+	eq(got.Meta, span.Meta)
+	eq(got.Metrics, span.Metrics)
+
+	_, err = DecodePayload(bytes.NewReader([]byte{0xc1}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
 func TestPackedSpans(t *testing.T) {
 	t.Run("integrity", func(t *testing.T) {
 		// whatever we push into the packedSpans should allow us to read the same content
@@ -147,7 +187,7 @@ func TestPackedSpans(t *testing.T) {
 		if ss.size() != 0 {
 			t.Fatalf("expected 0, got %d", ss.size())
 		}
-		if err := ss.add(&ddSpan{SpanID: 1}); err != nil {
+		if err := ss.add(&Span{SpanID: 1}); err != nil {
 			t.Fatal(err)
 		}
 		if ss.size() <= 0 {
@@ -162,11 +202,11 @@ func TestPackedSpans(t *testing.T) {
 			t.Run(strconv.Itoa(n), func(t *testing.T) {
 				ss.reset()
 				for i := 0; i < n; i++ {
-					if err := ss.add(&ddSpan{SpanID: uint64(i)}); err != nil {
+					if err := ss.add(&Span{SpanID: uint64(i)}); err != nil {
 						t.Fatal(err)
 					}
 				}
-				var got ddTrace
+				var got Trace
 				err := msgp.Decode(bytes.NewReader(ss.bytes()), &got)
 				if err != nil {
 					t.Fatal(err)
@@ -176,11 +216,11 @@ func TestPackedSpans(t *testing.T) {
 	})
 }
 
-// makeTrace returns a ddTrace of size n.
-func makeTrace(n int) ddTrace {
-	ddt := make(ddTrace, n)
+// makeTrace returns a Trace of size n.
+func makeTrace(n int) Trace {
+	ddt := make(Trace, n)
 	for i := 0; i < n; i++ {
-		span := ddSpan{SpanID: uint64(i)}
+		span := Span{SpanID: uint64(i)}
 		ddt[i] = span
 	}
 	return ddt
@@ -190,16 +230,16 @@ func makeTrace(n int) ddTrace {
 var idSeed uint64 = 123
 
 // makeSpan returns a new span having id as the trace ID.
-func makeSpan(id uint64) *ddSpan {
+func makeSpan(id uint64) *Span {
 	atomic.AddUint64(&idSeed, 1)
-	return &ddSpan{TraceID: id, SpanID: idSeed}
+	return &Span{TraceID: id, SpanID: idSeed}
 }
 
 // testPayload returns a payload used for testing.
 var testPayload = ddPayload{
-	ddTrace{*makeSpan(1), *makeSpan(1), *makeSpan(1)},
-	ddTrace{*makeSpan(2), *makeSpan(2)},
-	ddTrace{*makeSpan(3), *makeSpan(3), *makeSpan(3), *makeSpan(3)},
+	Trace{*makeSpan(1), *makeSpan(1), *makeSpan(1)},
+	Trace{*makeSpan(2), *makeSpan(2)},
+	Trace{*makeSpan(3), *makeSpan(3), *makeSpan(3), *makeSpan(3)},
 }
 
 // fillPayload adds the traces from testPayload to payload.
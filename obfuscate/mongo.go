@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// QuantizeMongo returns the given Mongo JSON query with all of its values
+// replaced by "?", keeping its keys and structure. Queries which are not
+// valid JSON are replaced by "?" altogether.
+//
+//	{"find": "users", "filter": {"age": {"$gt": 21}}}
+//
+// becomes
+//
+//	{"find":"?","filter":{"age":{"$gt":"?"}}}
+func QuantizeMongo(query string) string {
+	dec := json.NewDecoder(strings.NewReader(query))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	// stack holds the state of each enclosing object or array: whether it
+	// is an object, whether its next token is its first one and, for objects,
+	// whether the last token was a key.
+	type level struct{ object, first, key bool }
+	var stack []level
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return placeholder
+		}
+		isKey := false
+		if n := len(stack); n > 0 {
+			l := &stack[n-1]
+			if d, ok := tok.(json.Delim); !ok || d == '{' || d == '[' {
+				isKey = l.object && !l.key
+				switch {
+				case isKey && !l.first:
+					buf.WriteByte(',')
+				case !l.object && !l.first:
+					buf.WriteByte(',')
+				case !isKey && l.object:
+					buf.WriteByte(':')
+				}
+				l.first = false
+				if l.object {
+					l.key = !l.key
+				}
+			}
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			buf.WriteRune(rune(v))
+			switch v {
+			case '{', '[':
+				stack = append(stack, level{object: v == '{', first: true})
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if isKey {
+				b, _ := json.Marshal(v)
+				buf.Write(b)
+				continue
+			}
+			buf.WriteString(`"?"`)
+		default:
+			buf.WriteString(`"?"`)
+		}
+	}
+	if len(stack) > 0 {
+		return placeholder
+	}
+	return buf.String()
+}
@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import "testing"
+
+func TestQuantizeMongo(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			in:  `{"find": "users", "filter": {"age": {"$gt": 21}, "name": "bob"}}`,
+			out: `{"find":"?","filter":{"age":{"$gt":"?"},"name":"?"}}`,
+		},
+		{
+			in:  `{"insert": "users", "documents": [{"_id": 1, "tags": ["a", "b"], "ok": true, "x": null}]}`,
+			out: `{"insert":"?","documents":[{"_id":"?","tags":["?","?"],"ok":"?","x":"?"}]}`,
+		},
+		{
+			in:  `{"delete": "users", "deletes": [], "opts": {}}`,
+			out: `{"delete":"?","deletes":[],"opts":{}}`,
+		},
+		{in: `{"find": "users"`, out: "?"},
+		{in: `db.users.find()`, out: "?"},
+	} {
+		if got := QuantizeMongo(tt.in); got != tt.out {
+			t.Errorf("QuantizeMongo(%q):\ngot  %q\nwant %q", tt.in, got, tt.out)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package obfuscate quantizes database queries into resource names, replacing
+// their literal values so that similar queries share the same resource and no
+// sensitive data is exposed. It mimics the obfuscation done by the Datadog agent.
+package obfuscate
+
+// placeholder replaces obfuscated values.
+const placeholder = "?"
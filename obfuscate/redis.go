@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import "strings"
+
+// redisCompoundCommands holds the Redis commands whose first argument is a
+// subcommand, such as "CLIENT KILL".
+var redisCompoundCommands = map[string]bool{
+	"CLIENT":  true,
+	"CLUSTER": true,
+	"COMMAND": true,
+	"CONFIG":  true,
+	"DEBUG":   true,
+	"MEMORY":  true,
+	"OBJECT":  true,
+	"SCRIPT":  true,
+	"SLOWLOG": true,
+	"XGROUP":  true,
+	"XINFO":   true,
+}
+
+// maxRedisCommands is the maximum number of commands of a pipeline which are
+// kept when quantizing it.
+const maxRedisCommands = 3
+
+// QuantizeRedis returns the names of the Redis commands found in the given
+// newline-separated query, dropping their keys and arguments. For pipelines,
+// only the first few commands are kept and the others are replaced by "...".
+//
+//	SET user:1 bob
+//	EXPIRE user:1 3600
+//
+// becomes
+//
+//	SET EXPIRE
+func QuantizeRedis(query string) string {
+	var cmds []string
+	truncated := false
+	for _, line := range strings.Split(query, "\n") {
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if len(cmds) >= maxRedisCommands {
+			truncated = true
+			break
+		}
+		cmd := strings.ToUpper(args[0])
+		if redisCompoundCommands[cmd] && len(args) > 1 {
+			cmd += " " + strings.ToUpper(args[1])
+		}
+		cmds = append(cmds, cmd)
+	}
+	if truncated {
+		cmds = append(cmds, "...")
+	}
+	return strings.Join(cmds, " ")
+}
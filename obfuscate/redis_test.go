@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import "testing"
+
+func TestQuantizeRedis(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{in: "GET user:1", out: "GET"},
+		{in: "set user:1 bob EX 60", out: "SET"},
+		{in: "SET user:1 bob\nEXPIRE user:1 3600\n", out: "SET EXPIRE"},
+		{in: "CLIENT KILL 127.0.0.1:6379", out: "CLIENT KILL"},
+		{in: "CONFIG", out: "CONFIG"},
+		{in: "GET a\nGET b\nGET c\nGET d\nGET e", out: "GET GET GET ..."},
+		{in: "  \n", out: ""},
+	} {
+		if got := QuantizeRedis(tt.in); got != tt.out {
+			t.Errorf("QuantizeRedis(%q): got %q, want %q", tt.in, got, tt.out)
+		}
+	}
+}
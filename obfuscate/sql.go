@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sqlLiterals holds the keywords which denote literal values.
+var sqlLiterals = map[string]bool{
+	"NULL":  true,
+	"TRUE":  true,
+	"FALSE": true,
+}
+
+// QuantizeSQL returns the given SQL query with its comments removed, its
+// whitespace normalized and its literals (strings, numbers, booleans, NULL
+// and numbered parameters) replaced by "?". Lists of literals, such as the
+// ones found in IN clauses or VALUES, are collapsed into a single "( ? )".
+//
+//	SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'
+//
+// becomes
+//
+//	SELECT * FROM users WHERE id IN ( ? ) AND name = ?
+func QuantizeSQL(query string) string {
+	return joinSQL(groupSQL(tokenizeSQL(query)))
+}
+
+// tokenizeSQL splits the given query into tokens, replacing literals by the
+// placeholder and discarding comments.
+func tokenizeSQL(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"), c == '#':
+			// single line comment
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			tokens = append(tokens, placeholder)
+		case c == '"' || c == '`':
+			// quoted identifier
+			end := skipQuoted(query, i, c)
+			tokens = append(tokens, query[i:end])
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			i = skipNumber(query, i)
+			tokens = append(tokens, placeholder)
+		case c == '-' && i+1 < len(query) && isDigit(query[i+1]) && !endsOperand(tokens):
+			// negative number
+			i = skipNumber(query, i+1)
+			tokens = append(tokens, placeholder)
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			// numbered parameter, e.g. $1
+			i++
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+			tokens = append(tokens, placeholder)
+		case isIdentStart(query, i):
+			_, n := utf8.DecodeRuneInString(query[i:])
+			end := i + n
+			for end < len(query) && isIdentPart(query, end) {
+				_, n := utf8.DecodeRuneInString(query[end:])
+				end += n
+			}
+			word := query[i:end]
+			if sqlLiterals[strings.ToUpper(word)] {
+				word = placeholder
+			}
+			tokens = append(tokens, word)
+			i = end
+		default:
+			n := operatorLen(query[i:])
+			tokens = append(tokens, query[i:i+n])
+			i += n
+		}
+	}
+	return tokens
+}
+
+// skipQuoted returns the index following the end of the quoted string starting
+// at query[i]. Quotes are escaped by doubling them or by a backslash.
+func skipQuoted(query string, i int, quote byte) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// skipNumber returns the index following the end of the number starting at
+// query[i], which may be hexadecimal, decimal or use an exponent.
+func skipNumber(query string, i int) int {
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		i += 2
+		for i < len(query) && isHexDigit(query[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case isDigit(c), c == '.':
+			i++
+		case (c == 'e' || c == 'E') && i+1 < len(query):
+			i++
+			if query[i] == '+' || query[i] == '-' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// endsOperand reports whether the last token ends an operand, in which case
+// a following minus sign is a subtraction rather than a negative number.
+func endsOperand(tokens []string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	if last == placeholder || last == ")" {
+		return true
+	}
+	return isIdentStart(last, 0) && !isKeyword(last)
+}
+
+// isKeyword reports whether the given word is an SQL keyword which may
+// precede a negative number.
+func isKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "SELECT", "WHERE", "AND", "OR", "NOT", "IN", "VALUES", "SET", "LIMIT", "OFFSET", "THEN", "ELSE", "WHEN", "BETWEEN", "RETURN", "LIKE", "IS":
+		return true
+	}
+	return false
+}
+
+// operatorLen returns the length of the operator or punctuation at the start
+// of s.
+func operatorLen(s string) int {
+	for _, op := range []string{"<=>", "<>", "<=", ">=", "!=", "||", "::", "->>", "->", ":="} {
+		if strings.HasPrefix(s, op) {
+			return len(op)
+		}
+	}
+	_, n := utf8.DecodeRuneInString(s)
+	return n
+}
+
+// groupSQL collapses lists of placeholders, e.g. "( ?, ? )" into "( ? )",
+// as well as consecutive groups, e.g. "( ? ), ( ? )" into "( ? )".
+func groupSQL(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		out = append(out, tok)
+		n := len(out)
+		switch {
+		case tok == placeholder && n >= 3 && out[n-2] == "," && out[n-3] == placeholder && inGroup(out[:n-3]):
+			// "?, ?" inside parentheses
+			out = out[:n-2]
+		case tok == ")" && n >= 7 && isGroup(out[n-3:]) && out[n-4] == "," && isGroup(out[n-7:n-4]):
+			// "( ? ), ( ? )"
+			out = out[:n-4]
+		}
+	}
+	return out
+}
+
+// inGroup reports whether the placeholder following the given tokens is part
+// of a parenthesized list of placeholders.
+func inGroup(tokens []string) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i] {
+		case "(":
+			return true
+		case placeholder, ",":
+			continue
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isGroup reports whether the given tokens are "( ? )".
+func isGroup(tokens []string) bool {
+	return len(tokens) == 3 && tokens[0] == "(" && tokens[1] == placeholder && tokens[2] == ")"
+}
+
+// joinSQL joins the given tokens with single spaces, attaching commas and
+// semicolons to the preceding token and dots to both of their neighbours.
+func joinSQL(tokens []string) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && tok != "," && tok != ";" && tok != "." && tokens[i-1] != "." {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok)
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isIdentStart reports whether an identifier or keyword starts at s[i].
+func isIdentStart(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	if r == ':' && i+1 < len(s) {
+		// named parameter, e.g. :name
+		r, _ = utf8.DecodeRuneInString(s[i+1:])
+	}
+	return r == '_' || r == '@' || unicode.IsLetter(r)
+}
+
+// isIdentPart reports whether s[i] continues an identifier.
+func isIdentPart(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r == '_' || r == '.' || r == '$' || r == '@' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
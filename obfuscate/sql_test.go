@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package obfuscate
+
+import "testing"
+
+func TestQuantizeSQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			in:  "SELECT * FROM users WHERE id = 42",
+			out: "SELECT * FROM users WHERE id = ?",
+		},
+		{
+			in:  "SELECT name FROM users WHERE name = 'O''Brien' AND email = 'a\\'b'",
+			out: "SELECT name FROM users WHERE name = ? AND email = ?",
+		},
+		{
+			in:  "SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'",
+			out: "SELECT * FROM users WHERE id IN ( ? ) AND name = ?",
+		},
+		{
+			in:  "INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z')",
+			out: "INSERT INTO t ( a, b ) VALUES ( ? )",
+		},
+		{
+			in:  "SELECT  a,\n\tb\nFROM t -- trailing comment\nWHERE x = -1.5e3 /* inline */ AND y = 0xFF;",
+			out: "SELECT a, b FROM t WHERE x = ? AND y = ?",
+		},
+		{
+			in:  "UPDATE t SET a = a - 1, b = TRUE, c = NULL WHERE id = $1",
+			out: "UPDATE t SET a = a - ?, b = ?, c = ? WHERE id = ?",
+		},
+		{
+			in:  `SELECT "weird name", t.col FROM "schema"."table" t WHERE t.x >= :min`,
+			out: `SELECT "weird name", t.col FROM "schema"."table" t WHERE t.x >= :min`,
+		},
+		{
+			in:  "SELECT COUNT(*) FROM t WHERE created_at > '2020-01-01'::date",
+			out: "SELECT COUNT ( * ) FROM t WHERE created_at > ? :: date",
+		},
+		{
+			in:  "SELECT * FROM users WHERE id = ?",
+			out: "SELECT * FROM users WHERE id = ?",
+		},
+	} {
+		if got := QuantizeSQL(tt.in); got != tt.out {
+			t.Errorf("QuantizeSQL(%q):\ngot  %q\nwant %q", tt.in, got, tt.out)
+		}
+		if got := QuantizeSQL(tt.out); got != tt.out {
+			t.Errorf("QuantizeSQL is not idempotent for %q: got %q", tt.out, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// defaultTraceRateLimit specifies the default maximum number of traces per
+	// second which may be kept by the sampling rules.
+	defaultTraceRateLimit = 100
+
+	// keyLimitPSR holds the effective rate of the rate limiter at the time it
+	// allowed or rejected a trace.
+	keyLimitPSR = "_dd.limit_psr"
+)
+
+// rateLimiter is a token bucket allowing up to limit events per second, with
+// bursts of the same size, or of one event for limits below one per second. It
+// also tracks its effective rate, i.e. the ratio of allowed events over the
+// current and the previous second. It is not safe for concurrent use.
+type rateLimiter struct {
+	limit  float64
+	burst  float64 // maximum number of tokens
+	tokens float64
+	last   time.Time // time at which tokens were last added
+
+	window   time.Time // start of the current one-second window
+	allowed  float64   // number of allowed events in the current window
+	seen     float64   // number of events in the current window
+	prevRate float64   // effective rate of the previous window; -1 if none
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+// newRateLimiter returns a rateLimiter allowing up to limit events per second,
+// or nil if limit is negative.
+func newRateLimiter(limit float64) *rateLimiter {
+	if limit < 0 {
+		return nil
+	}
+	burst := math.Max(limit, 1)
+	if limit == 0 {
+		burst = 0
+	}
+	return &rateLimiter{
+		limit:    limit,
+		burst:    burst,
+		tokens:   burst,
+		prevRate: -1,
+		now:      time.Now,
+	}
+}
+
+// allow reports whether the next event is allowed, along with the effective
+// rate of the limiter. A nil rateLimiter allows all events.
+func (l *rateLimiter) allow() (bool, float64) {
+	if l == nil {
+		return true, 1
+	}
+	now := l.now()
+	if l.last.IsZero() {
+		l.last, l.window = now, now
+	}
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.limit
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+	if d := now.Sub(l.window); d >= time.Second {
+		l.prevRate = -1
+		if d < 2*time.Second && l.seen > 0 {
+			// the previous window is the one which just ended
+			l.prevRate = l.allowed / l.seen
+		}
+		l.window, l.allowed, l.seen = now, 0, 0
+	}
+	l.seen++
+	ok := l.tokens >= 1
+	if ok {
+		l.tokens--
+		l.allowed++
+	}
+	rate := l.allowed / l.seen
+	if l.prevRate >= 0 {
+		rate = (rate + l.prevRate) / 2
+	}
+	return ok, rate
+}
@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("bucket", func(t *testing.T) {
+		eq := equalFunc(t)
+		l := newRateLimiter(2)
+		now := time.Unix(100, 0)
+		l.now = func() time.Time { return now }
+
+		allowed := func() bool {
+			ok, _ := l.allow()
+			return ok
+		}
+		eq(allowed(), true)
+		eq(allowed(), true)
+		eq(allowed(), false)
+
+		now = now.Add(500 * time.Millisecond) // one token
+		eq(allowed(), true)
+		eq(allowed(), false)
+
+		now = now.Add(time.Hour) // bucket is capped
+		eq(allowed(), true)
+		eq(allowed(), true)
+		eq(allowed(), false)
+	})
+
+	t.Run("below-one", func(t *testing.T) {
+		eq := equalFunc(t)
+		l := newRateLimiter(0.5)
+		now := time.Unix(100, 0)
+		l.now = func() time.Time { return now }
+
+		allowed := func() bool {
+			ok, _ := l.allow()
+			return ok
+		}
+		eq(allowed(), true)
+		eq(allowed(), false)
+
+		now = now.Add(time.Second) // half a token
+		eq(allowed(), false)
+
+		now = now.Add(time.Second)
+		eq(allowed(), true)
+
+		now = now.Add(time.Hour) // bucket is capped at one
+		eq(allowed(), true)
+		eq(allowed(), false)
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		eq := equalFunc(t)
+		l := newRateLimiter(0)
+		now := time.Unix(100, 0)
+		l.now = func() time.Time { return now }
+
+		ok, _ := l.allow()
+		eq(ok, false)
+		now = now.Add(time.Hour)
+		ok, _ = l.allow()
+		eq(ok, false)
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		eq := equalFunc(t)
+		l := newRateLimiter(1)
+		now := time.Unix(100, 0)
+		l.now = func() time.Time { return now }
+
+		_, rate := l.allow()
+		eq(rate, 1.)
+		_, rate = l.allow()
+		eq(rate, 0.5)
+
+		now = now.Add(time.Second) // previous window rate is 0.5
+		ok, rate := l.allow()
+		eq(ok, true)
+		eq(rate, 0.75)
+
+		now = now.Add(time.Hour) // no previous window
+		_, rate = l.allow()
+		eq(rate, 1.)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		eq := equalFunc(t)
+		l := newRateLimiter(-1)
+		eq(l == nil, true)
+		ok, rate := l.allow()
+		eq(ok, true)
+		eq(rate, 1.)
+	})
+}
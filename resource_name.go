@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"strings"
+
+	"github.com/DataDog/opencensus-go-exporter-datadog/obfuscate"
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// span semantic conventions used for deriving resource names and span types, see:
+// https://github.com/open-telemetry/opentelemetry-specification/tree/master/specification/trace/semantic_conventions
+const (
+	keyHTTPMethod  = "http.method"
+	keyHTTPRoute   = "http.route"
+	keyRPCService  = "rpc.service"
+	keyRPCMethod   = "rpc.method"
+	keyDBStatement = "db.statement"
+
+	keyDBSystem        = "db.system"
+	keyMessagingSystem = "messaging.system"
+	keyRPCSystem       = "rpc.system"
+)
+
+// resourceName returns the Datadog resource name of s. It uses the ResourceNamer
+// hook when one is set, falling back to deriving the name from the semantic
+// conventions followed by the span attributes.
+func (c *spanConverter) resourceName(s *export.SpanData) string {
+	if c.opts.ResourceNamer != nil {
+		if name := c.opts.ResourceNamer(s); name != "" {
+			return name
+		}
+	}
+	return defaultResourceName(s)
+}
+
+// defaultResourceName derives a resource name from the span attributes:
+//
+//   - HTTP spans having a route are named after the method and the route,
+//     e.g. "GET /users/{id}".
+//   - RPC spans are named after the service and the method, e.g.
+//     "helloworld.Greeter/SayHello".
+//   - Database spans are named after their normalized statement.
+//
+// Any other span is named after the OpenTelemetry span name.
+func defaultResourceName(s *export.SpanData) string {
+	var method, route, rpcService, rpcMethod, statement string
+	for _, attr := range s.Attributes {
+		if attr.Value.Type() != label.STRING {
+			continue
+		}
+		switch attr.Key {
+		case keyHTTPMethod:
+			method = attr.Value.AsString()
+		case keyHTTPRoute:
+			route = attr.Value.AsString()
+		case keyRPCService:
+			rpcService = attr.Value.AsString()
+		case keyRPCMethod:
+			rpcMethod = attr.Value.AsString()
+		case keyDBStatement:
+			statement = attr.Value.AsString()
+		}
+	}
+	switch {
+	case method != "" && route != "":
+		return strings.ToUpper(method) + " " + route
+	case rpcService != "" && rpcMethod != "":
+		return rpcService + "/" + rpcMethod
+	case statement != "":
+		return normalizeStatement(statement)
+	}
+	return s.Name
+}
+
+// normalizeStatement collapses all the whitespace in the given database
+// statement into single spaces.
+func normalizeStatement(stmt string) string {
+	return strings.Join(strings.Fields(stmt), " ")
+}
+
+// obfuscateQuery replaces the literals found in the resource and in the
+// "db.statement" tag of the database span s, which was converted from sd. They
+// are quantized according to "db.system", except for resources explicitly set
+// using the "resource.name" attribute, which are only quantized on SQL spans.
+// The statements of other database systems are left as they are.
+func obfuscateQuery(s *Span, sd *export.SpanData) {
+	var dbSystem, statement string
+	explicit := false
+	for _, attr := range sd.Attributes {
+		switch attr.Key {
+		case keyDBSystem:
+			dbSystem = attr.Value.Emit()
+		case keyDBStatement:
+			statement = attr.Value.Emit()
+		case ext.ResourceName:
+			explicit = true
+		}
+	}
+	isSQL := sqlSystems[dbSystem] || s.Type == ext.SpanTypeSQL
+	var quantize func(string) string
+	switch {
+	case isSQL:
+		quantize = obfuscate.QuantizeSQL
+	case dbSystem == "redis":
+		quantize = obfuscate.QuantizeRedis
+	case dbSystem == "mongodb":
+		quantize = obfuscate.QuantizeMongo
+	default:
+		return
+	}
+	switch {
+	case explicit:
+		if isSQL {
+			s.Resource = quantize(s.Resource)
+		}
+	case statement == "" || s.Resource != normalizeStatement(statement):
+		// not derived from the statement, e.g. named by ResourceNamer
+	default:
+		s.Resource = quantize(statement)
+	}
+	if v, ok := s.Meta[keyDBStatement]; ok {
+		s.Meta[keyDBStatement] = quantize(v)
+	}
+}
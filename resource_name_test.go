@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestDefaultResourceName(t *testing.T) {
+	for name, tt := range map[string]struct {
+		attrs []label.KeyValue
+		want  string
+	}{
+		"default": {
+			want: "span-name",
+		},
+		"http": {
+			attrs: []label.KeyValue{
+				label.String(keyHTTPMethod, "get"),
+				label.String(keyHTTPRoute, "/users/{id}"),
+				label.String("http.target", "/users/123"),
+			},
+			want: "GET /users/{id}",
+		},
+		"http-no-route": {
+			attrs: []label.KeyValue{label.String(keyHTTPMethod, "GET")},
+			want:  "span-name",
+		},
+		"rpc": {
+			attrs: []label.KeyValue{
+				label.String("rpc.system", "grpc"),
+				label.String(keyRPCService, "helloworld.Greeter"),
+				label.String(keyRPCMethod, "SayHello"),
+			},
+			want: "helloworld.Greeter/SayHello",
+		},
+		"db": {
+			attrs: []label.KeyValue{
+				label.String("db.system", "postgresql"),
+				label.String(keyDBStatement, "SELECT *\n\tFROM users\n\tWHERE id = 1 "),
+			},
+			want: "SELECT * FROM users WHERE id = 1",
+		},
+		"non-string": {
+			attrs: []label.KeyValue{
+				label.Int64(keyHTTPMethod, 1),
+				label.String(keyHTTPRoute, "/users/{id}"),
+			},
+			want: "span-name",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &export.SpanData{Name: "span-name", Attributes: tt.attrs}
+			equalFunc(t)(defaultResourceName(s), tt.want)
+		})
+	}
+}
+
+func TestResourceNamer(t *testing.T) {
+	e := newTraceExporter(Options{
+		Service: "my-service",
+		ResourceNamer: func(s *export.SpanData) string {
+			if s.Name == "skip" {
+				return ""
+			}
+			return "custom"
+		},
+	})
+	defer e.stop()
+
+	t.Run("hook", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		equalFunc(t)(e.convertSpan(&sd).Resource, "custom")
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		sd.Name = "skip"
+		equalFunc(t)(e.convertSpan(&sd).Resource, "skip")
+	})
+
+	t.Run("attribute", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		sd.Attributes = []label.KeyValue{label.String(ext.ResourceName, "explicit")}
+		equalFunc(t)(e.convertSpan(&sd).Resource, "explicit")
+	})
+}
+
+func TestObfuscateQuery(t *testing.T) {
+	for name, tt := range map[string]struct {
+		opts  Options
+		attrs []label.KeyValue
+		want  string
+		stmt  string // the "db.statement" tag
+	}{
+		"sql": {
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "postgresql"),
+				label.String(keyDBStatement, "SELECT * FROM users\n\tWHERE id IN (1, 2) AND name = 'bob'"),
+			},
+			want: "SELECT * FROM users WHERE id IN ( ? ) AND name = ?",
+			stmt: "SELECT * FROM users WHERE id IN ( ? ) AND name = ?",
+		},
+		"sql-explicit": {
+			attrs: []label.KeyValue{
+				label.String(ext.SpanType, ext.SpanTypeSQL),
+				label.String(ext.ResourceName, "DELETE FROM users WHERE id = 42"),
+			},
+			want: "DELETE FROM users WHERE id = ?",
+		},
+		"explicit-non-sql": {
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "redis"),
+				label.String(keyDBStatement, "GET user:1"),
+				label.String(ext.ResourceName, "GET user:1"),
+			},
+			want: "GET user:1",
+			stmt: "GET",
+		},
+		"redis": {
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "redis"),
+				label.String(keyDBStatement, "SET user:1 bob\nEXPIRE user:1 60"),
+			},
+			want: "SET EXPIRE",
+			stmt: "SET EXPIRE",
+		},
+		"mongodb": {
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "mongodb"),
+				label.String(keyDBStatement, `{"find": "users", "filter": {"name": "bob"}}`),
+			},
+			want: `{"find":"?","filter":{"name":"?"}}`,
+			stmt: `{"find":"?","filter":{"name":"?"}}`,
+		},
+		"other": {
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "cassandra"),
+				label.String(keyDBStatement, "SELECT * FROM users WHERE id = 1"),
+			},
+			want: "SELECT * FROM users WHERE id = 1",
+			stmt: "SELECT * FROM users WHERE id = 1",
+		},
+		"disabled": {
+			opts: Options{DisableQueryObfuscation: true},
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "mysql"),
+				label.String(keyDBStatement, "SELECT * FROM users WHERE id = 1"),
+			},
+			want: "SELECT * FROM users WHERE id = 1",
+			stmt: "SELECT * FROM users WHERE id = 1",
+		},
+		"resource-namer": {
+			opts: Options{ResourceNamer: func(*export.SpanData) string { return "users.by_id" }},
+			attrs: []label.KeyValue{
+				label.String(keyDBSystem, "mysql"),
+				label.String(keyDBStatement, "SELECT * FROM users WHERE id = 1"),
+			},
+			want: "users.by_id",
+			stmt: "SELECT * FROM users WHERE id = ?",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sd := *spanPairs["child"].oc
+			sd.Attributes = tt.attrs
+			eq := equalFunc(t)
+			span := ConvertSpan(&sd, tt.opts)
+			eq(span.Resource, tt.want)
+			eq(span.Meta[keyDBStatement], tt.stmt)
+		})
+	}
+}
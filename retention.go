@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// defaultRetentionPerSecond specifies the default maximum number of traces per
+// second kept by the retention policy.
+const defaultRetentionPerSecond = 10
+
+// RetentionPolicy specifies which traces are kept regardless of the sampling
+// rates provided by the agent. The zero value keeps no trace.
+type RetentionPolicy struct {
+	// KeepErrors specifies whether to keep the traces containing an error.
+	KeepErrors bool
+
+	// SlowThreshold specifies the duration above which the traces whose local
+	// root span is slower are kept. Zero disables it.
+	SlowThreshold time.Duration
+
+	// MinSpans specifies the number of spans from which traces are kept. Zero
+	// disables it.
+	MinSpans int
+
+	// MaxPerSecond specifies the maximum number of traces per second kept by
+	// the policy. It defaults to 10. A negative value disables the limit.
+	MaxPerSecond float64
+}
+
+// retainer applies a RetentionPolicy.
+type retainer struct {
+	keepErrors bool
+	slow       time.Duration
+	minSpans   int
+	limiter    *rateLimiter // nil when unlimited
+}
+
+// newRetainer returns a retainer applying the given policy, or nil if the
+// policy keeps no trace.
+func newRetainer(p RetentionPolicy) *retainer {
+	if !p.KeepErrors && p.SlowThreshold <= 0 && p.MinSpans <= 0 {
+		return nil
+	}
+	if p.MaxPerSecond == 0 {
+		p.MaxPerSecond = defaultRetentionPerSecond
+	}
+	return &retainer{
+		keepErrors: p.KeepErrors,
+		slow:       p.SlowThreshold,
+		minSpans:   p.MinSpans,
+		limiter:    newRateLimiter(p.MaxPerSecond),
+	}
+}
+
+// wants reports whether the trace of the given spans should be kept, because
+// one of them is an error or a slow local root, or because there are enough of
+// them.
+func (r *retainer) wants(spans ...*Span) bool {
+	if r == nil {
+		return false
+	}
+	if r.minSpans > 0 && len(spans) >= r.minSpans {
+		return true
+	}
+	for _, span := range spans {
+		if (r.keepErrors && span.Error != 0) ||
+			(r.slow > 0 && span.localRoot && time.Duration(span.Duration) >= r.slow) {
+			return true
+		}
+	}
+	return false
+}
+
+// retain upgrades the given automatic sampling decision to user keep when the
+// policy wants the trace of the given spans and its budget allows it. It
+// reports whether the decision was upgraded.
+func (r *retainer) retain(d *samplingDecision, spans ...*Span) bool {
+	if d.priority != ext.PriorityAutoReject && d.priority != ext.PriorityAutoKeep {
+		// user decisions always take precedence
+		return false
+	}
+	if !r.wants(spans...) {
+		return false
+	}
+	if ok, _ := r.limiter.allow(); !ok {
+		return false
+	}
+	d.priority = ext.PriorityUserKeep
+	return true
+}
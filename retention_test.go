@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestRetainer(t *testing.T) {
+	errSpan := &Span{Error: 1}
+	slowRoot := &Span{Duration: int64(2 * time.Second), localRoot: true}
+	slowChild := &Span{Duration: int64(2 * time.Second)}
+	fastRoot := &Span{Duration: int64(time.Millisecond), localRoot: true}
+
+	t.Run("wants", func(t *testing.T) {
+		eq := equalFunc(t)
+		r := newRetainer(RetentionPolicy{KeepErrors: true, SlowThreshold: time.Second})
+		eq(r.wants(errSpan), true)
+		eq(r.wants(slowRoot), true)
+		eq(r.wants(slowChild), false)
+		eq(r.wants(fastRoot), false)
+
+		r = newRetainer(RetentionPolicy{SlowThreshold: time.Second})
+		eq(r.wants(errSpan), false)
+		eq(r.wants(slowRoot), true)
+
+		r = newRetainer(RetentionPolicy{MinSpans: 3})
+		eq(r.wants(errSpan, fastRoot), false)
+		eq(r.wants(errSpan, slowChild, fastRoot), true)
+
+		r = newRetainer(RetentionPolicy{MaxPerSecond: 5})
+		eq(r == nil, true)
+		eq(r.wants(errSpan), false)
+	})
+
+	t.Run("retain", func(t *testing.T) {
+		eq := equalFunc(t)
+		r := newRetainer(RetentionPolicy{KeepErrors: true, MaxPerSecond: 1})
+		r.limiter.now = func() time.Time { return time.Unix(100, 0) }
+
+		d := samplingDecision{priority: ext.PriorityUserReject}
+		eq(r.retain(&d, errSpan), false)
+		eq(d.priority, float64(ext.PriorityUserReject))
+
+		d = samplingDecision{priority: ext.PriorityAutoReject}
+		eq(r.retain(&d, fastRoot), false)
+		eq(r.retain(&d, errSpan), true)
+		eq(d.priority, float64(ext.PriorityUserKeep))
+
+		// over budget
+		d = samplingDecision{priority: ext.PriorityAutoReject}
+		eq(r.retain(&d, errSpan), false)
+		eq(d.priority, float64(ext.PriorityAutoReject))
+	})
+}
+
+func TestRetentionExporter(t *testing.T) {
+	t.Run("tail-sampling", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{
+			Service:      "my-service",
+			Retention:    RetentionPolicy{KeepErrors: true},
+			TailSampling: true,
+		})
+		defer e.stop()
+		eq(e.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0}}`))), nil)
+
+		child := e.convertSpan(spanPairs["child"].oc)
+		failed := e.convertSpan(spanPairs["server_error_5xx"].oc)
+		eq(failed.Error, int32(1))
+		root := e.convertSpan(spanPairs["root"].oc)
+		e.sampleTrace([]*Span{child, failed, root})
+		eq(child.Metrics[keySamplingPriority], float64(ext.PriorityUserKeep))
+		eq(failed.Metrics[keySamplingPriority], float64(ext.PriorityUserKeep))
+		eq(root.Metrics[keySamplingPriority], float64(ext.PriorityUserKeep))
+		eq(root.Metrics[keySamplingPriorityRate], 0.)
+	})
+
+	t.Run("no-tail-sampling", func(t *testing.T) {
+		eq := equalFunc(t)
+		var errs []error
+		e := newTraceExporter(Options{
+			Service:   "my-service",
+			Retention: RetentionPolicy{KeepErrors: true},
+			OnError:   func(err error) { errs = append(errs, err) },
+		})
+		defer e.stop()
+		eq(len(errs), 1)
+		eq(e.retainer == nil, true)
+	})
+}
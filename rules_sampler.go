@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// keyRulePSR holds the rate of the sampling rule which decided the priority
+// of a span.
+const keyRulePSR = "_dd.rule_psr"
+
+// SamplingRule specifies the sampling rate of the traces whose spans match its
+// patterns. Patterns are case-insensitive globs, where "*" matches any sequence
+// of characters and "?" matches a single character. Empty patterns match any
+// value.
+type SamplingRule struct {
+	// Service specifies the pattern matching the span service.
+	Service string `json:"service,omitempty"`
+
+	// Name specifies the pattern matching the span operation name.
+	Name string `json:"name,omitempty"`
+
+	// Resource specifies the pattern matching the span resource.
+	Resource string `json:"resource,omitempty"`
+
+	// Tags specifies the patterns matching the values of the given span tags,
+	// all of which must be present.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Rate specifies the rate, between 0 and 1, at which the matching traces
+	// are kept. In JSON, it defaults to 1 when omitted.
+	Rate float64 `json:"sample_rate"`
+}
+
+// parseSamplingRules parses the given JSON array of sampling rules, as found in
+// the DD_TRACE_SAMPLING_RULES environment variable. The rate of the rules
+// defaults to 1.
+func parseSamplingRules(str string) ([]SamplingRule, error) {
+	var rules []SamplingRule
+	err := parseRules(str, func() (interface{}, *float64) {
+		rules = append(rules, SamplingRule{})
+		r := &rules[len(rules)-1]
+		return r, &r.Rate
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// parseRules parses the given JSON array of rules. For each of them, newRule
+// returns the rule to decode it into, valid until the next call, along with
+// its rate, which defaults to 1 and must be between 0 and 1.
+func parseRules(str string, newRule func() (rule interface{}, rate *float64)) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(str), &raw); err != nil {
+		return err
+	}
+	for i, r := range raw {
+		rule, rate := newRule()
+		*rate = 1
+		if err := json.Unmarshal(r, rule); err != nil {
+			return err
+		}
+		if _, ok := clampRate(*rate); !ok {
+			return fmt.Errorf("sample_rate %v of rule %d is not between 0 and 1", *rate, i)
+		}
+	}
+	return nil
+}
+
+// clampRate returns the given sampling rate clamped between 0 and 1, and
+// reports whether it was valid.
+func clampRate(rate float64) (float64, bool) {
+	switch {
+	case rate > 1:
+		return 1, false
+	case rate >= 0:
+		return rate, true
+	default:
+		// negative or NaN
+		return 0, false
+	}
+}
+
+// samplingRule is the compiled form of a SamplingRule.
+type samplingRule struct {
+	service  *regexp.Regexp
+	name     *regexp.Regexp
+	resource *regexp.Regexp
+	tags     map[string]*regexp.Regexp
+	rate     float64
+}
+
+// match reports whether the given span matches the rule.
+func (r *samplingRule) match(spn *Span) bool {
+	if !globMatch(r.service, spn.Service) || !globMatch(r.name, spn.Name) || !globMatch(r.resource, spn.Resource) {
+		return false
+	}
+	for k, re := range r.tags {
+		v, ok := spn.Meta[k]
+		if !ok {
+			m, ok := spn.Metrics[k]
+			if !ok {
+				return false
+			}
+			v = strconv.FormatFloat(m, 'g', -1, 64)
+		}
+		if !globMatch(re, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesSampler applies the user-defined sampling rules to spans, limiting the
+// rate of the traces they keep.
+type rulesSampler struct {
+	rules   []samplingRule
+	limiter *rateLimiter // nil when unlimited
+}
+
+// newRulesSampler returns a rulesSampler applying the given rules and keeping
+// at most limit traces per second, or nil if there are no rules. A negative
+// limit disables rate limiting. Rates which are not between 0 and 1 are
+// clamped and reported using onError.
+func newRulesSampler(rules []SamplingRule, limit float64, onError func(error)) *rulesSampler {
+	if len(rules) == 0 {
+		return nil
+	}
+	rs := &rulesSampler{
+		rules:   make([]samplingRule, len(rules)),
+		limiter: newRateLimiter(limit),
+	}
+	for i, r := range rules {
+		rate, ok := clampRate(r.Rate)
+		if !ok {
+			onError(fmt.Errorf("sampling rule %d: sample_rate %v is not between 0 and 1, using %v", i, r.Rate, rate))
+		}
+		rs.rules[i] = samplingRule{
+			service:  compileGlob(r.Service),
+			name:     compileGlob(r.Name),
+			resource: compileGlob(r.Resource),
+			rate:     rate,
+		}
+		if len(r.Tags) > 0 {
+			rs.rules[i].tags = make(map[string]*regexp.Regexp, len(r.Tags))
+			for k, v := range r.Tags {
+				rs.rules[i].tags[k] = compileGlob(v)
+			}
+		}
+	}
+	return rs
+}
+
+// apply applies the first rule matching the given span, if any, setting its
+// sampling priority to user keep or user reject and recording the rule rate.
+// Traces kept beyond the rate limit are downgraded to auto reject, and the
+// effective rate of the limiter is recorded. It reports whether a rule matched.
+func (rs *rulesSampler) apply(spn *Span) bool {
+	if rs == nil {
+		return false
+	}
+	for i := range rs.rules {
+		r := &rs.rules[i]
+		if !r.match(spn) {
+			continue
+		}
+		spn.Metrics[keyRulePSR] = r.rate
+		if !sampledByRate(spn.TraceID, r.rate) {
+			spn.Metrics[keySamplingPriority] = ext.PriorityUserReject
+			return true
+		}
+		ok, rate := rs.limiter.allow()
+		if ok {
+			spn.Metrics[keySamplingPriority] = ext.PriorityUserKeep
+		} else {
+			spn.Metrics[keySamplingPriority] = ext.PriorityAutoReject
+		}
+		if rs.limiter != nil {
+			spn.Metrics[keyLimitPSR] = rate
+		}
+		return true
+	}
+	return false
+}
+
+// compileGlob returns a regular expression matching the given case-insensitive
+// glob pattern, or nil if the pattern is empty or matches anything.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" || pattern == "*" {
+		return nil
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	return regexp.MustCompile("(?is)^" + expr + "$")
+}
+
+// globMatch reports whether v matches the glob compiled into re. A nil re
+// matches any value.
+func globMatch(re *regexp.Regexp, v string) bool {
+	return re == nil || re.MatchString(v)
+}
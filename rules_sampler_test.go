@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestCompileGlob(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, value string
+		match          bool
+	}{
+		{pattern: "", value: "anything", match: true},
+		{pattern: "*", value: "anything", match: true},
+		{pattern: "payment", value: "payment", match: true},
+		{pattern: "payment", value: "PAYMENT", match: true},
+		{pattern: "payment", value: "payments", match: false},
+		{pattern: "pay*", value: "payments", match: true},
+		{pattern: "GET /health?", value: "GET /healthz", match: true},
+		{pattern: "a.b", value: "axb", match: false},
+	} {
+		equalFunc(t)(globMatch(compileGlob(tt.pattern), tt.value), tt.match)
+	}
+}
+
+func TestRulesSampler(t *testing.T) {
+	mkSpan := func(service, name, resource string) *Span {
+		return &Span{
+			TraceID:  1,
+			Service:  service,
+			Name:     name,
+			Resource: resource,
+			Meta:     map[string]string{"tier": "gold"},
+			Metrics:  map[string]float64{"http.status_code": 200},
+		}
+	}
+	rs := newRulesSampler([]SamplingRule{
+		{Service: "payment*", Rate: 1},
+		{Name: "http.server", Resource: "GET /health", Rate: 0},
+		{Tags: map[string]string{"tier": "gold", "http.status_code": "2??"}, Rate: 0.5},
+	}, -1, nil)
+
+	for name, tt := range map[string]struct {
+		span     *Span
+		matched  bool
+		priority float64
+		rate     float64
+	}{
+		"service":  {span: mkSpan("payment-api", "http.server", "POST /pay"), matched: true, priority: ext.PriorityUserKeep, rate: 1},
+		"resource": {span: mkSpan("web", "http.server", "GET /health"), matched: true, priority: ext.PriorityUserReject, rate: 0},
+		"tags":     {span: mkSpan("web", "http.server", "GET /users"), matched: true, priority: ext.PriorityUserKeep, rate: 0.5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			eq(rs.apply(tt.span), tt.matched)
+			eq(tt.span.Metrics[keySamplingPriority], tt.priority)
+			eq(tt.span.Metrics[keyRulePSR], tt.rate)
+		})
+	}
+
+	t.Run("no-match", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := mkSpan("web", "http.server", "GET /users")
+		span.Meta["tier"] = "silver"
+		eq(rs.apply(span), false)
+		_, ok := span.Metrics[keySamplingPriority]
+		eq(ok, false)
+	})
+
+	t.Run("invalid-rate", func(t *testing.T) {
+		eq := equalFunc(t)
+		var errs []error
+		rs := newRulesSampler([]SamplingRule{{Service: "web", Rate: -1}, {Rate: 2}}, -1, func(err error) { errs = append(errs, err) })
+		eq(len(errs), 2)
+		for i := uint64(0); i < 100; i++ {
+			span := mkSpan("web", "", "")
+			span.TraceID = i * 7919
+			rs.apply(span)
+			eq(span.Metrics[keySamplingPriority], float64(ext.PriorityUserReject))
+			eq(span.Metrics[keyRulePSR], 0.)
+		}
+		span := mkSpan("db", "", "")
+		rs.apply(span)
+		eq(span.Metrics[keySamplingPriority], float64(ext.PriorityUserKeep))
+		eq(span.Metrics[keyRulePSR], 1.)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		eq := equalFunc(t)
+		eq(newRulesSampler(nil, -1, nil) == nil, true)
+		eq(newRulesSampler(nil, -1, nil).apply(mkSpan("web", "", "")), false)
+	})
+}
+
+func TestParseSamplingRules(t *testing.T) {
+	eq := equalFunc(t)
+	rules, err := parseSamplingRules(`[{"service": "payment"}, {"name": "*.health", "tags": {"env": "prod"}, "sample_rate": 0.01}, {"sample_rate": 0}]`)
+	eq(err, nil)
+	eq(rules, []SamplingRule{
+		{Service: "payment", Rate: 1},
+		{Name: "*.health", Tags: map[string]string{"env": "prod"}, Rate: 0.01},
+		{Rate: 0},
+	})
+
+	_, err = parseSamplingRules(`{"service": "payment"}`)
+	eq(err != nil, true)
+
+	_, err = parseSamplingRules(`[{"service": "payment", "sample_rate": -1}]`)
+	eq(err != nil, true)
+	_, err = parseSamplingRules(`[{"service": "payment", "sample_rate": 1.5}]`)
+	eq(err != nil, true)
+}
+
+func TestSamplingRulesExporter(t *testing.T) {
+	eq := equalFunc(t)
+	me := newTestTraceExporterWithOptions(t, Options{
+		Service:       "mock.exporter",
+		SamplingRules: []SamplingRule{{Resource: "/a/b", Rate: 0}},
+	})
+	other := *spanPairs["slash"].oc
+	other.SpanContext.TraceID = trace.ID{15: 1}
+	me.exportSpan(spanPairs["root"].oc)
+	me.exportSpan(&other)
+	me.stop()
+
+	payloads := me.payloads()
+	eq(len(payloads), 1)
+	priorities := map[string]float64{}
+	for _, tr := range payloads[0] {
+		for _, span := range tr {
+			priorities[span.Resource] = span.Metrics[keySamplingPriority]
+		}
+	}
+	eq(priorities["/a/b"], float64(ext.PriorityUserReject))
+	eq(priorities["/"], float64(ext.PriorityAutoKeep))
+}
+
+func TestRulesSamplerLimit(t *testing.T) {
+	eq := equalFunc(t)
+	rs := newRulesSampler([]SamplingRule{{Rate: 1}}, 2, nil)
+	now := time.Unix(100, 0)
+	rs.limiter.now = func() time.Time { return now }
+
+	var priorities []float64
+	for i := 0; i < 3; i++ {
+		span := &Span{TraceID: uint64(i), Metrics: map[string]float64{}}
+		eq(rs.apply(span), true)
+		priorities = append(priorities, span.Metrics[keySamplingPriority])
+		if i == 2 {
+			eq(span.Metrics[keyLimitPSR], 2./3)
+		}
+	}
+	eq(priorities, []float64{ext.PriorityUserKeep, ext.PriorityUserKeep, ext.PriorityAutoReject})
+}
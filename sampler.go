@@ -11,6 +11,7 @@ import (
 	"math"
 	"sync"
 
+	"go.opentelemetry.io/otel/api/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
 
@@ -62,7 +63,7 @@ func (ps *prioritySampler) readRatesJSON(rc io.ReadCloser) error {
 }
 
 // getRate returns the sampling rate to be used for the given span.
-func (ps *prioritySampler) getRate(spn *ddSpan) float64 {
+func (ps *prioritySampler) getRate(spn *Span) float64 {
 	key := "service:" + spn.Service + ",env:" + spn.Meta[ext.Environment]
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
@@ -72,8 +73,8 @@ func (ps *prioritySampler) getRate(spn *ddSpan) float64 {
 	return ps.defaultRate
 }
 
-// applyPriority applies sampling priority to the given ddSpan.
-func (ps *prioritySampler) applyPriority(spn *ddSpan) {
+// applyPriority applies sampling priority to the given Span.
+func (ps *prioritySampler) applyPriority(spn *Span) {
 	rate := ps.getRate(spn)
 	if sampledByRate(spn.TraceID, rate) {
 		spn.Metrics[keySamplingPriority] = ext.PriorityAutoKeep
@@ -82,3 +83,20 @@ func (ps *prioritySampler) applyPriority(spn *ddSpan) {
 	}
 	spn.Metrics[keySamplingPriorityRate] = rate
 }
+
+// upstreamPriority returns the sampling priority decided upstream for a span
+// having a remote parent, as propagated by the trace flags of its context:
+// debug traces are kept as if by the user, and the sampled flag tells apart
+// kept traces from rejected ones. Deferred traces carry no decision, in which
+// case it returns false.
+func upstreamPriority(sc trace.SpanContext) (float64, bool) {
+	switch {
+	case sc.TraceFlags&trace.FlagsDebug != 0:
+		return ext.PriorityUserKeep, true
+	case sc.TraceFlags&trace.FlagsDeferred != 0:
+		return 0, false
+	case sc.IsSampled():
+		return ext.PriorityAutoKeep, true
+	}
+	return ext.PriorityAutoReject, true
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"testing"
 
+	"go.opentelemetry.io/otel/api/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 
 	"github.com/stretchr/testify/assert"
@@ -19,8 +20,8 @@ import (
 
 func TestPrioritySampler(t *testing.T) {
 	// create a new span with given service/env
-	mkSpan := func(svc, env string) *ddSpan {
-		s := &ddSpan{Service: svc, Meta: map[string]string{}}
+	mkSpan := func(svc, env string) *Span {
+		s := &Span{Service: svc, Meta: map[string]string{}}
 		if env != "" {
 			s.Meta["env"] = env
 		}
@@ -143,7 +144,7 @@ func TestPrioritySampler(t *testing.T) {
 			)),
 		))
 
-		testSpan1 := &ddSpan{
+		testSpan1 := &Span{
 			Name:    "http.request",
 			Metrics: map[string]float64{},
 		}
@@ -165,3 +166,65 @@ func TestPrioritySampler(t *testing.T) {
 		assert.EqualValues(0.5, testSpan1.Metrics[keySamplingPriorityRate])
 	})
 }
+
+func TestUpstreamPriority(t *testing.T) {
+	for name, tt := range map[string]struct {
+		flags    byte
+		priority float64
+		ok       bool
+	}{
+		"sampled":     {flags: trace.FlagsSampled, priority: ext.PriorityAutoKeep, ok: true},
+		"not-sampled": {flags: 0, priority: ext.PriorityAutoReject, ok: true},
+		"debug":       {flags: trace.FlagsSampled | trace.FlagsDebug, priority: ext.PriorityUserKeep, ok: true},
+		"deferred":    {flags: trace.FlagsDeferred},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			priority, ok := upstreamPriority(trace.SpanContext{TraceFlags: tt.flags})
+			assert.Equal(tt.ok, ok)
+			assert.Equal(tt.priority, priority)
+		})
+	}
+
+	t.Run("remote-parent", func(t *testing.T) {
+		assert := assert.New(t)
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		// reject all traces started locally
+		assert.NoError(e.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0}}`))))
+
+		sd := *spanPairs["child"].oc
+		sd.HasRemoteParent = true
+		span := e.convertSpan(&sd)
+		e.sampleTrace([]*Span{span})
+		assert.EqualValues(ext.PriorityAutoKeep, span.Metrics[keySamplingPriority])
+		_, ok := span.Metrics[keySamplingPriorityRate]
+		assert.False(ok)
+
+		sd.SpanContext.TraceFlags = 0
+		sd.SpanContext.TraceID[0] = 42
+		span = e.convertSpan(&sd)
+		e.sampleTrace([]*Span{span})
+		assert.EqualValues(ext.PriorityAutoReject, span.Metrics[keySamplingPriority])
+
+		span = e.convertSpan(spanPairs["root"].oc)
+		e.sampleTrace([]*Span{span})
+		assert.EqualValues(ext.PriorityAutoKeep, span.Metrics[keySamplingPriority], "same trace as the remote child")
+	})
+
+	t.Run("multi-span", func(t *testing.T) {
+		assert := assert.New(t)
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		assert.NoError(e.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0}}`))))
+
+		// a local child ends before the remote child it descends from; the
+		// upstream decision only applies when they are sampled together.
+		sd := *spanPairs["root"].oc
+		sd.HasRemoteParent = true
+		child, root := e.convertSpan(spanPairs["child"].oc), e.convertSpan(&sd)
+		e.sampleTrace([]*Span{child, root})
+		assert.EqualValues(ext.PriorityAutoKeep, child.Metrics[keySamplingPriority])
+		assert.EqualValues(ext.PriorityAutoKeep, root.Metrics[keySamplingPriority])
+	})
+}
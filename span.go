@@ -8,12 +8,15 @@ package datadog
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"net/http"
+	"regexp"
 	"strconv"
 
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/label"
 	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"google.golang.org/grpc/codes"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
@@ -46,50 +49,251 @@ type codeDetails struct {
 	status  int    // corresponding HTTP status code
 }
 
+// statusDetails returns the details of the given status code.
+func statusDetails(c codes.Code) codeDetails {
+	if code, ok := statusCodes[c]; ok {
+		return code
+	}
+	return codeDetails{
+		message: "ERR_CODE_" + strconv.FormatInt(int64(c), 10),
+		status:  http.StatusInternalServerError,
+	}
+}
+
+// ErrorClassifier decides whether the given span is an error. When it is, it
+// also returns the error type and message which will be attached to the
+// Datadog span. Empty values are not attached.
+type ErrorClassifier func(s *export.SpanData) (isError bool, errType, errMsg string)
+
+// DefaultErrorClassifier is the ErrorClassifier used when none is specified.
+// It maps the span status code to its HTTP equivalent and reports client
+// spans having a 4xx code and all other spans having a 5xx code as errors,
+// using the status name as error type and the status message as error message.
+// Spans having an exception event are reported as errors too.
+func DefaultErrorClassifier(s *export.SpanData) (isError bool, errType, errMsg string) {
+	code := statusDetails(s.StatusCode)
+	switch s.SpanKind {
+	case trace.SpanKindClient:
+		isError = code.status/100 == 4
+	default:
+		isError = code.status/100 == 5
+	}
+	if !isError {
+		// the details are taken from the exception event
+		return hasException(s.MessageEvents), "", ""
+	}
+	return true, code.message, s.StatusMessage
+}
+
+// defaultOperationName specifies the operation name given to spans which
+// were not created by a named instrumentation library.
+const defaultOperationName = "opentelemetry"
+
+// opNameReg matches the characters which are not allowed in operation names.
+var opNameReg = regexp.MustCompile("[^a-zA-Z0-9_.]+")
+
+// operationName returns the Datadog operation name of s, derived from its
+// instrumentation library and its kind, e.g. "otelhttp.server".
+func operationName(s *export.SpanData) string {
+	lib := s.InstrumentationLibrary.Name
+	if lib == "" {
+		return defaultOperationName
+	}
+	name := opNameReg.ReplaceAllString(lib, "_")
+	if s.SpanKind == trace.SpanKindUnspecified {
+		return name
+	}
+	return name + "." + s.SpanKind.String()
+}
+
+// sqlSystems holds the values of the "db.system" attribute which denote
+// SQL databases.
+var sqlSystems = map[string]bool{
+	"db2":        true,
+	"derby":      true,
+	"h2":         true,
+	"hsqldb":     true,
+	"mariadb":    true,
+	"mssql":      true,
+	"mysql":      true,
+	"oracle":     true,
+	"other_sql":  true,
+	"postgresql": true,
+	"sqlite":     true,
+}
+
+// cacheSystems holds the values of the "db.system" attribute which denote
+// caches.
+var cacheSystems = map[string]bool{
+	"memcached": true,
+	"redis":     true,
+}
+
+// spanType infers the Datadog span type of s from its attributes, following
+// the semantic conventions. Spans without any known attribute fall back to
+// "server" or "client", depending on their kind.
+func spanType(s *export.SpanData) string {
+	var dbSystem, httpMethod string
+	var messaging, rpc bool
+	for _, attr := range s.Attributes {
+		switch attr.Key {
+		case keyDBSystem:
+			dbSystem = attr.Value.Emit()
+		case keyMessagingSystem:
+			messaging = true
+		case keyRPCSystem:
+			rpc = true
+		case keyHTTPMethod:
+			httpMethod = attr.Value.Emit()
+		}
+	}
+	switch {
+	case cacheSystems[dbSystem]:
+		return ext.AppTypeCache
+	case sqlSystems[dbSystem]:
+		return ext.SpanTypeSQL
+	case dbSystem != "":
+		return ext.AppTypeDB
+	case messaging:
+		return ext.SpanTypeMessageConsumer
+	case rpc:
+		return ext.AppTypeRPC
+	}
+	switch s.SpanKind {
+	case trace.SpanKindServer:
+		if httpMethod != "" {
+			return ext.SpanTypeWeb
+		}
+		return "server"
+	case trace.SpanKindClient:
+		if httpMethod != "" {
+			return ext.SpanTypeHTTP
+		}
+		return "client"
+	}
+	return ""
+}
+
+// ConvertSpan converts the given OpenTelemetry span into the Datadog span which
+// an exporter created using the same options would send to the agent, prior to
+// sampling. Unlike NewExporter, it does not read the unset options from the
+// environment. It allows testing and reusing the mapping outside of the
+// exporter.
+func ConvertSpan(s *export.SpanData, o Options) *Span {
+	return newSpanConverter(o).convertSpan(s)
+}
+
+// spanConverter converts OpenTelemetry spans to Datadog spans.
+type spanConverter struct {
+	opts     Options
+	rules    *attributeRules // compiled opts.AttributeRules
+	scrubber *scrubber       // nil when scrubbing is disabled
+	limits   spanLimits
+}
+
+// newSpanConverter returns a spanConverter using the given options, after
+// filling in their defaults.
+func newSpanConverter(o Options) *spanConverter {
+	if o.Service == "" {
+		o.Service = defaultService
+	}
+	if o.ErrorClassifier == nil {
+		o.ErrorClassifier = DefaultErrorClassifier
+	}
+	if o.ArraySeparator == "" {
+		o.ArraySeparator = defaultArraySeparator
+	}
+	if o.MaxEvents == 0 {
+		o.MaxEvents = defaultMaxEvents
+	}
+	if o.MaxEventsSize == 0 {
+		o.MaxEventsSize = defaultMaxEventsSize
+	}
+	if o.MaxLinksSize == 0 {
+		o.MaxLinksSize = defaultMaxLinksSize
+	}
+	if len(o.ScrubKeys) == 0 {
+		o.ScrubKeys = defaultScrubKeys
+	}
+	if o.MaxTagKeyLength == 0 {
+		o.MaxTagKeyLength = defaultMaxTagKeyLength
+	}
+	if o.MaxTagValueLength == 0 {
+		o.MaxTagValueLength = defaultMaxTagValueLength
+	}
+	if o.MaxMetaEntries == 0 {
+		o.MaxMetaEntries = defaultMaxMetaEntries
+	}
+	if o.MaxResourceLength == 0 {
+		o.MaxResourceLength = defaultMaxResourceLength
+	}
+	if o.MaxServiceLength == 0 {
+		o.MaxServiceLength = defaultMaxServiceLength
+	}
+	c := &spanConverter{
+		opts:  o,
+		rules: o.AttributeRules.compile(),
+		limits: spanLimits{
+			keyLength:      o.MaxTagKeyLength,
+			valueLength:    o.MaxTagValueLength,
+			metaEntries:    o.MaxMetaEntries,
+			resourceLength: o.MaxResourceLength,
+			serviceLength:  o.MaxServiceLength,
+		},
+	}
+	if !o.DisableScrubbing {
+		c.scrubber = newScrubber(o.ScrubKeys)
+	}
+	return c
+}
+
+// traceID returns the Datadog trace ID mapped from the given OpenTelemetry trace
+// ID, along with the high 64 bits of the latter.
+func (c *spanConverter) traceID(id trace.ID) (low, high uint64) {
+	high = binary.BigEndian.Uint64(id[:8])
+	if c.opts.TraceIDMapping == TraceIDHash {
+		h := fnv.New64a()
+		h.Write(id[:])
+		return h.Sum64(), high
+	}
+	return binary.BigEndian.Uint64(id[8:]), high
+}
+
 // convertSpan takes an OpenTelemetry span and returns a Datadog span.
-func (e *traceExporter) convertSpan(s *export.SpanData) *ddSpan {
+func (c *spanConverter) convertSpan(s *export.SpanData) *Span {
 	startNano := s.StartTime.UnixNano()
-	span := &ddSpan{
-		TraceID:  binary.BigEndian.Uint64(s.SpanContext.TraceID[8:]),
+	span := &Span{
 		SpanID:   binary.BigEndian.Uint64(s.SpanContext.SpanID[:]),
-		Name:     "opentelemetry",
-		Resource: s.Name,
-		Service:  e.opts.Service,
+		Name:     operationName(s),
+		Resource: c.resourceName(s),
+		Type:     spanType(s),
+		Service:  c.opts.Service,
 		Start:    startNano,
 		Duration: s.EndTime.UnixNano() - startNano,
 		Metrics:  map[string]float64{},
 		Meta:     map[string]string{},
 	}
+	span.TraceID, span.traceIDHigh = c.traceID(s.SpanContext.TraceID)
+	if span.traceIDHigh != 0 && c.opts.TraceIDMapping == TraceIDLow64 {
+		span.Meta[keyTraceIDHigh] = fmt.Sprintf("%016x", span.traceIDHigh)
+	}
 	if s.ParentSpanID.IsValid() {
 		span.ParentID = binary.BigEndian.Uint64(s.ParentSpanID[:])
 	}
-
-	code, ok := statusCodes[s.StatusCode]
-	if !ok {
-		code = codeDetails{
-			message: "ERR_CODE_" + strconv.FormatInt(int64(s.StatusCode), 10),
-			status:  http.StatusInternalServerError,
+	span.localRoot = !s.ParentSpanID.IsValid() || s.HasRemoteParent
+	if s.HasRemoteParent {
+		if p, ok := upstreamPriority(s.SpanContext); ok {
+			span.Metrics[keySamplingPriority] = p
 		}
 	}
 
-	switch s.SpanKind {
-	case trace.SpanKindClient:
-		span.Type = "client"
-		if code.status/100 == 4 {
-			span.Error = 1
-		}
-	case trace.SpanKindServer:
-		span.Type = "server"
-		fallthrough
-	default:
-		if code.status/100 == 5 {
-			span.Error = 1
+	code := statusDetails(s.StatusCode)
+	if isErr, typ, msg := c.opts.ErrorClassifier(s); isErr {
+		span.Error = 1
+		if typ != "" {
+			span.Meta[ext.ErrorType] = typ
 		}
-	}
-
-	if span.Error == 1 {
-		span.Meta[ext.ErrorType] = code.message
-		if msg := s.StatusMessage; msg != "" {
+		if msg != "" {
 			span.Meta[ext.ErrorMsg] = msg
 		}
 	}
@@ -100,15 +304,65 @@ func (e *traceExporter) convertSpan(s *export.SpanData) *ddSpan {
 		span.Meta[keyStatusDescription] = msg
 	}
 
-	for _, attr := range e.opts.GlobalTags {
-		setTag(span, string(attr.Key), attr.Value)
+	if c.opts.Env != "" {
+		span.Meta[ext.Environment] = c.opts.Env
+	}
+	if c.opts.Version != "" {
+		span.Meta[keyVersion] = c.opts.Version
+	}
+	for _, attr := range c.opts.GlobalTags {
+		c.setTag(span, string(attr.Key), attr.Value)
+	}
+	setResource(span, s.Resource)
+	if c.opts.Measured != nil && c.opts.Measured(s) {
+		span.Metrics[keyMeasured] = 1
+	}
+	if lib := s.InstrumentationLibrary; lib.Name != "" {
+		span.Meta[keyLibraryName] = lib.Name
+		if lib.Version != "" {
+			span.Meta[keyLibraryVersion] = lib.Version
+		}
 	}
 	for _, attr := range s.Attributes {
-		setTag(span, string(attr.Key), attr.Value)
+		val := c.scrubber.scrub(string(attr.Key), attr.Value)
+		key, val, ok := c.rules.apply(string(attr.Key), val)
+		if ok {
+			c.setTag(span, key, val)
+		}
+	}
+	if !c.opts.DisableQueryObfuscation {
+		obfuscateQuery(span, s)
+	}
+	if span.localRoot {
+		// entry point of the service: either the root of the trace or the
+		// local root of a distributed trace. The spans whose service differs
+		// from the one of their parent are only known from the whole trace;
+		// see setTopLevel.
+		span.Metrics[keyTopLevel] = 1
 	}
+	setExceptionError(span, s.MessageEvents)
+	setLinks(span, s.Links, s.DroppedLinkCount, c.opts.MaxLinksSize, c.traceID, c.scrubber)
+	setEvents(span, s.MessageEvents, s.DroppedMessageEventCount, c.opts.MaxEvents, c.opts.MaxEventsSize, c.scrubber)
+	c.limits.apply(span)
 	return span
 }
 
+// setTopLevel marks the spans of the given trace as top-level when their parent
+// is part of the trace and has a different service. Local roots are marked when
+// converted, while the other spans can only be marked when their parent is
+// known, i.e. when TailSampling is enabled.
+func setTopLevel(spans []*Span) {
+	services := make(map[uint64]string, len(spans))
+	for _, span := range spans {
+		services[span.SpanID] = span.Service
+	}
+	for _, span := range spans {
+		if parent, ok := services[span.ParentID]; ok && !span.localRoot && span.Service != parent {
+			span.Metrics[keyTopLevel] = 1
+		}
+	}
+}
+
 const (
 	keySamplingPriority     = "_sampling_priority_v1"
 	keyStatusDescription    = "opentelemetry.status_description"
@@ -116,9 +370,50 @@ const (
 	keyStatus               = "opentelemetry.status"
 	keySpanName             = "span.name"
 	keySamplingPriorityRate = "_sampling_priority_rate_v1"
+	keyVersion              = "version"
+	keyTraceIDHigh          = "_dd.p.tid"
+	keyTopLevel             = "_top_level"
+	keyMeasured             = "_dd.measured"
+	keyLibraryName          = "otel.library.name"
+	keyLibraryVersion       = "otel.library.version"
+
+	// resource semantic conventions, see:
+	// https://github.com/open-telemetry/opentelemetry-specification/tree/master/specification/resource/semantic_conventions
+	keyDeploymentEnvironment = "deployment.environment"
+	keyServiceVersion        = "service.version"
 )
 
-func setTag(s *ddSpan, key string, val label.Value) {
+// setResource applies the service, environment and version found in the
+// attributes of the given resource to s.
+func setResource(s *Span, r *resource.Resource) {
+	if r == nil {
+		return
+	}
+	for _, attr := range r.Attributes() {
+		if attr.Value.Type() != label.STRING {
+			continue
+		}
+		switch attr.Key {
+		case ext.ServiceName:
+			s.Service = attr.Value.AsString()
+		case keyDeploymentEnvironment:
+			s.Meta[ext.Environment] = attr.Value.AsString()
+		case keyServiceVersion:
+			s.Meta[keyVersion] = attr.Value.AsString()
+		}
+	}
+}
+
+// setTag sets the given tag on s, encoding arrays as specified by the options.
+func (c *spanConverter) setTag(s *Span, key string, val label.Value) {
+	if val.Type() == label.ARRAY && key != ext.Error {
+		setArrayTag(s, key, val, c.opts.ArrayEncoding, c.opts.ArraySeparator)
+		return
+	}
+	setTag(s, key, val)
+}
+
+func setTag(s *Span, key string, val label.Value) {
 	if key == ext.Error {
 		setError(s, val)
 		return
@@ -145,13 +440,12 @@ func setTag(s *ddSpan, key string, val label.Value) {
 	case label.UINT64:
 		setMetric(s, key, float64(val.AsUint64()))
 	case label.ARRAY:
-		// should never happen according to docs, nevertheless
-		// we should account for this to avoid exceptions
-		setStringTag(s, key, fmt.Sprintf("%v", val.AsArray()))
+		// default encoding; (*spanConverter).setTag applies the options
+		setArrayTag(s, key, val, ArrayEncodingJSON, defaultArraySeparator)
 	}
 }
 
-func setMetric(s *ddSpan, key string, v float64) {
+func setMetric(s *Span, key string, v float64) {
 	switch key {
 	case ext.SamplingPriority:
 		s.Metrics[keySamplingPriority] = v
@@ -160,7 +454,7 @@ func setMetric(s *ddSpan, key string, v float64) {
 	}
 }
 
-func setStringTag(s *ddSpan, key, v string) {
+func setStringTag(s *Span, key, v string) {
 	switch key {
 	case ext.ServiceName:
 		s.Service = v
@@ -176,12 +470,22 @@ func setStringTag(s *ddSpan, key, v string) {
 		}
 	case keySpanName:
 		s.Name = v
+	case keyMeasured:
+		if v != "false" {
+			setMetric(s, keyMeasured, 1)
+		} else {
+			delete(s.Metrics, keyMeasured)
+		}
+	case keyDeploymentEnvironment:
+		s.Meta[ext.Environment] = v
+	case keyServiceVersion:
+		s.Meta[keyVersion] = v
 	default:
 		s.Meta[key] = v
 	}
 }
 
-func setError(s *ddSpan, val label.Value) {
+func setError(s *Span, val label.Value) {
 	switch val.Type() {
 	case label.STRING:
 		s.Error = 1
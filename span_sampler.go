@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// metrics set on the spans kept by span sampling rules, which tell the agent
+// to keep them even though their trace is dropped.
+const (
+	keySpanSamplingMechanism    = "_dd.span_sampling.mechanism"
+	keySpanSamplingRuleRate     = "_dd.span_sampling.rule_rate"
+	keySpanSamplingMaxPerSecond = "_dd.span_sampling.max_per_second"
+
+	// samplingMechanismSingleSpan identifies the single span sampling mechanism.
+	samplingMechanismSingleSpan = 8
+)
+
+// SpanSamplingRule specifies the rate at which the spans matching its patterns
+// are kept when their trace is rejected. Patterns are case-insensitive globs,
+// where "*" matches any sequence of characters and "?" matches a single
+// character. Empty patterns match any value.
+type SpanSamplingRule struct {
+	// Service specifies the pattern matching the span service.
+	Service string `json:"service,omitempty"`
+
+	// Name specifies the pattern matching the span operation name.
+	Name string `json:"name,omitempty"`
+
+	// Rate specifies the rate, between 0 and 1, at which the matching spans
+	// are kept. In JSON, it defaults to 1 when omitted.
+	Rate float64 `json:"sample_rate"`
+
+	// MaxPerSecond specifies the maximum number of matching spans kept per
+	// second. Zero means no limit.
+	MaxPerSecond float64 `json:"max_per_second,omitempty"`
+}
+
+// parseSpanSamplingRules parses the given JSON array of span sampling rules, as
+// found in the DD_SPAN_SAMPLING_RULES environment variable. The rate of the
+// rules defaults to 1.
+func parseSpanSamplingRules(str string) ([]SpanSamplingRule, error) {
+	var rules []SpanSamplingRule
+	err := parseRules(str, func() (interface{}, *float64) {
+		rules = append(rules, SpanSamplingRule{})
+		r := &rules[len(rules)-1]
+		return r, &r.Rate
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// spanSamplingRule is the compiled form of a SpanSamplingRule.
+type spanSamplingRule struct {
+	service      *regexp.Regexp
+	name         *regexp.Regexp
+	rate         float64
+	maxPerSecond float64
+	limiter      *rateLimiter // nil when unlimited
+}
+
+// spanSampler applies the span sampling rules to the spans of rejected traces.
+type spanSampler struct {
+	rules []spanSamplingRule
+}
+
+// newSpanSampler returns a spanSampler applying the given rules, or nil if
+// there are none. Rates which are not between 0 and 1 are clamped and reported
+// using onError.
+func newSpanSampler(rules []SpanSamplingRule, onError func(error)) *spanSampler {
+	if len(rules) == 0 {
+		return nil
+	}
+	ss := &spanSampler{rules: make([]spanSamplingRule, len(rules))}
+	for i, r := range rules {
+		rate, ok := clampRate(r.Rate)
+		if !ok {
+			onError(fmt.Errorf("span sampling rule %d: sample_rate %v is not between 0 and 1, using %v", i, r.Rate, rate))
+		}
+		ss.rules[i] = spanSamplingRule{
+			service:      compileGlob(r.Service),
+			name:         compileGlob(r.Name),
+			rate:         rate,
+			maxPerSecond: r.MaxPerSecond,
+		}
+		if r.MaxPerSecond > 0 {
+			ss.rules[i].limiter = newRateLimiter(r.MaxPerSecond)
+		}
+	}
+	return ss
+}
+
+// apply marks the given span to be kept when its trace is rejected and the
+// first rule it matches samples it, within the rule's limit. It reports whether
+// the span was marked.
+func (ss *spanSampler) apply(span *Span) bool {
+	if ss == nil || span.Metrics[keySamplingPriority] > 0 {
+		return false
+	}
+	for i := range ss.rules {
+		r := &ss.rules[i]
+		if !globMatch(r.service, span.Service) || !globMatch(r.name, span.Name) {
+			continue
+		}
+		if !sampledByRate(span.SpanID, r.rate) {
+			return false
+		}
+		if ok, _ := r.limiter.allow(); !ok {
+			return false
+		}
+		span.Metrics[keySpanSamplingMechanism] = samplingMechanismSingleSpan
+		span.Metrics[keySpanSamplingRuleRate] = r.rate
+		if r.maxPerSecond > 0 {
+			span.Metrics[keySpanSamplingMaxPerSecond] = r.maxPerSecond
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestSpanSampler(t *testing.T) {
+	mkSpan := func(service, name string, priority float64) *Span {
+		return &Span{
+			SpanID:  1,
+			Service: service,
+			Name:    name,
+			Metrics: map[string]float64{keySamplingPriority: priority},
+		}
+	}
+	ss := newSpanSampler([]SpanSamplingRule{
+		{Service: "billing", Name: "db.query", Rate: 1, MaxPerSecond: 1},
+		{Service: "billing", Rate: 0},
+		{Name: "http.*", Rate: 1},
+	}, nil)
+	ss.rules[0].limiter.now = func() time.Time { return time.Unix(100, 0) }
+
+	t.Run("rejected", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := mkSpan("billing", "db.query", ext.PriorityAutoReject)
+		eq(ss.apply(span), true)
+		eq(span.Metrics[keySpanSamplingMechanism], float64(samplingMechanismSingleSpan))
+		eq(span.Metrics[keySpanSamplingRuleRate], 1.)
+		eq(span.Metrics[keySpanSamplingMaxPerSecond], 1.)
+
+		// over the limit of the rule
+		span = mkSpan("billing", "db.query", ext.PriorityUserReject)
+		eq(ss.apply(span), false)
+		_, ok := span.Metrics[keySpanSamplingMechanism]
+		eq(ok, false)
+	})
+
+	t.Run("first-match", func(t *testing.T) {
+		eq := equalFunc(t)
+		eq(ss.apply(mkSpan("billing", "http.request", ext.PriorityAutoReject)), false)
+
+		span := mkSpan("web", "http.request", ext.PriorityAutoReject)
+		eq(ss.apply(span), true)
+		_, ok := span.Metrics[keySpanSamplingMaxPerSecond]
+		eq(ok, false)
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := mkSpan("web", "http.request", ext.PriorityAutoKeep)
+		eq(ss.apply(span), false)
+		eq(len(span.Metrics), 1)
+	})
+
+	t.Run("invalid-rate", func(t *testing.T) {
+		eq := equalFunc(t)
+		var errs []error
+		ss := newSpanSampler([]SpanSamplingRule{{Rate: -1}}, func(err error) { errs = append(errs, err) })
+		eq(len(errs), 1)
+		eq(ss.rules[0].rate, 0.)
+		eq(ss.apply(mkSpan("web", "http.request", ext.PriorityAutoReject)), false)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		eq := equalFunc(t)
+		eq(newSpanSampler(nil, nil) == nil, true)
+		eq(newSpanSampler(nil, nil).apply(mkSpan("web", "http.request", 0)), false)
+	})
+}
+
+func TestParseSpanSamplingRules(t *testing.T) {
+	eq := equalFunc(t)
+	rules, err := parseSpanSamplingRules(`[{"service": "billing", "name": "db.query", "sample_rate": 0.1, "max_per_second": 50}, {"name": "http.*"}]`)
+	eq(err, nil)
+	eq(rules, []SpanSamplingRule{
+		{Service: "billing", Name: "db.query", Rate: 0.1, MaxPerSecond: 50},
+		{Name: "http.*", Rate: 1},
+	})
+
+	_, err = parseSpanSamplingRules(`[{"service": 1}]`)
+	eq(err != nil, true)
+	_, err = parseSpanSamplingRules(`[{"service": "billing", "sample_rate": 2}]`)
+	eq(err != nil, true)
+}
+
+func TestSpanSamplingExporter(t *testing.T) {
+	eq := equalFunc(t)
+	me := newTestTraceExporterWithOptions(t, Options{
+		Service:           "mock.exporter",
+		SpanSamplingRules: []SpanSamplingRule{{Name: "opentelemetry", Rate: 1}},
+	})
+	eq(me.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0}}`))), nil)
+	me.exportSpan(spanPairs["root"].oc)
+	me.stop()
+
+	payloads := me.payloads()
+	eq(len(payloads), 1)
+	span := payloads[0][0][0]
+	eq(span.Metrics[keySamplingPriority], float64(ext.PriorityAutoReject))
+	eq(span.Metrics[keySpanSamplingMechanism], float64(samplingMechanismSingleSpan))
+}
@@ -13,6 +13,8 @@ import (
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/label"
 	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"google.golang.org/grpc/codes"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
@@ -22,10 +24,10 @@ var (
 	testEndTime   = testStartTime.Add(10 * time.Second)
 )
 
-// spanPairs holds a set of trace.SpanData and its corresponding conversion to a ddSpan.
+// spanPairs holds a set of trace.SpanData and its corresponding conversion to a Span.
 var spanPairs = map[string]struct {
 	oc *export.SpanData
-	dd *ddSpan
+	dd *Span
 }{
 	"root": {
 		oc: &export.SpanData{
@@ -46,7 +48,7 @@ var spanPairs = map[string]struct {
 			StatusCode:    0,
 			StatusMessage: "status-msg",
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
@@ -54,15 +56,18 @@ var spanPairs = map[string]struct {
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
-			Metrics:  map[string]float64{"int64": 1},
+			Metrics:  map[string]float64{"int64": 1, keyTopLevel: 1},
 			Service:  "my-service",
 			Meta: map[string]string{
+				keyTraceIDHigh:       "0102030405060708",
 				"bool":               "true",
 				"str":                "abc",
 				keyStatus:            "OK",
 				keyStatusCode:        "0",
 				keyStatusDescription: "status-msg",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"child": {
@@ -78,7 +83,7 @@ var spanPairs = map[string]struct {
 			StartTime:    testStartTime,
 			EndTime:      testEndTime,
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			ParentID: 578437695752307201,
@@ -90,9 +95,11 @@ var spanPairs = map[string]struct {
 			Metrics:  map[string]float64{},
 			Service:  "my-service",
 			Meta: map[string]string{
-				keyStatus:     "OK",
-				keyStatusCode: "0",
+				keyTraceIDHigh: "0102030405060708",
+				keyStatus:      "OK",
+				keyStatusCode:  "0",
 			},
+			traceIDHigh: 72623859790382856,
 		},
 	},
 	"server_error_4xx": {
@@ -109,7 +116,7 @@ var spanPairs = map[string]struct {
 			StatusCode:    codes.Canceled,
 			StatusMessage: "status-msg",
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "server",
@@ -117,14 +124,17 @@ var spanPairs = map[string]struct {
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
-			Metrics:  map[string]float64{},
+			Metrics:  map[string]float64{keyTopLevel: 1},
 			Error:    0,
 			Service:  "my-service",
 			Meta: map[string]string{
+				keyTraceIDHigh:       "0102030405060708",
 				keyStatus:            "CANCELLED",
 				keyStatusCode:        "1",
 				keyStatusDescription: "status-msg",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"server_error_5xx": {
@@ -141,7 +151,7 @@ var spanPairs = map[string]struct {
 			StatusCode:    codes.Internal,
 			StatusMessage: "status-msg",
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "server",
@@ -149,16 +159,19 @@ var spanPairs = map[string]struct {
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
-			Metrics:  map[string]float64{},
+			Metrics:  map[string]float64{keyTopLevel: 1},
 			Error:    1,
 			Service:  "my-service",
 			Meta: map[string]string{
+				keyTraceIDHigh:       "0102030405060708",
 				ext.ErrorMsg:         "status-msg",
 				ext.ErrorType:        "INTERNAL",
 				keyStatus:            "INTERNAL",
 				keyStatusCode:        "13",
 				keyStatusDescription: "status-msg",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"client_error_4xx": {
@@ -175,7 +188,7 @@ var spanPairs = map[string]struct {
 			StatusCode:    codes.Canceled,
 			StatusMessage: "status-msg",
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
@@ -183,16 +196,19 @@ var spanPairs = map[string]struct {
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
-			Metrics:  map[string]float64{},
+			Metrics:  map[string]float64{keyTopLevel: 1},
 			Error:    1,
 			Service:  "my-service",
 			Meta: map[string]string{
+				keyTraceIDHigh:       "0102030405060708",
 				ext.ErrorMsg:         "status-msg",
 				ext.ErrorType:        "CANCELLED",
 				keyStatus:            "CANCELLED",
 				keyStatusCode:        "1",
 				keyStatusDescription: "status-msg",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"client_error_5xx": {
@@ -209,7 +225,7 @@ var spanPairs = map[string]struct {
 			StatusCode:    codes.Internal,
 			StatusMessage: "status-msg",
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
@@ -217,14 +233,17 @@ var spanPairs = map[string]struct {
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
-			Metrics:  map[string]float64{},
+			Metrics:  map[string]float64{keyTopLevel: 1},
 			Error:    0,
 			Service:  "my-service",
 			Meta: map[string]string{
+				keyTraceIDHigh:       "0102030405060708",
 				keyStatus:            "INTERNAL",
 				keyStatusCode:        "13",
 				keyStatusDescription: "status-msg",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"tags": {
@@ -246,7 +265,7 @@ var spanPairs = map[string]struct {
 				label.Int64(ext.SamplingPriority, ext.PriorityUserReject),
 			},
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "other-type",
@@ -256,13 +275,17 @@ var spanPairs = map[string]struct {
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
 			Metrics: map[string]float64{
 				keySamplingPriority: ext.PriorityUserReject,
+				keyTopLevel:         1,
 			},
 			Service: "other-service",
 			Error:   1,
 			Meta: map[string]string{
-				keyStatus:     "OK",
-				keyStatusCode: "0",
+				keyTraceIDHigh: "0102030405060708",
+				keyStatus:      "OK",
+				keyStatusCode:  "0",
 			},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
 		},
 	},
 	"slash": {
@@ -277,7 +300,7 @@ var spanPairs = map[string]struct {
 			StartTime: testStartTime,
 			EndTime:   testEndTime,
 		},
-		dd: &ddSpan{
+		dd: &Span{
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
@@ -287,10 +310,13 @@ var spanPairs = map[string]struct {
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
 			Service:  "my-service",
 			Meta: map[string]string{
-				keyStatus:     "OK",
-				keyStatusCode: "0",
+				keyTraceIDHigh: "0102030405060708",
+				keyStatus:      "OK",
+				keyStatusCode:  "0",
 			},
-			Metrics: map[string]float64{},
+			traceIDHigh: 72623859790382856,
+			localRoot:   true,
+			Metrics:     map[string]float64{keyTopLevel: 1},
 		},
 	},
 }
@@ -309,6 +335,16 @@ func TestConvertSpan(t *testing.T) {
 	}
 }
 
+func TestConvertSpanPublic(t *testing.T) {
+	for name, tt := range spanPairs {
+		t.Run(name, func(t *testing.T) {
+			if got := ConvertSpan(tt.oc, Options{Service: "my-service"}); !reflect.DeepEqual(got, tt.dd) {
+				t.Fatalf("\nGot:\n%#v\n\nWant:\n%#v\n", got, tt.dd)
+			}
+		})
+	}
+}
+
 func TestGlobalTags(t *testing.T) {
 	e := newTraceExporter(Options{
 		Service:    "my-service",
@@ -322,6 +358,310 @@ func TestGlobalTags(t *testing.T) {
 	}
 }
 
+func TestInstrumentationLibrary(t *testing.T) {
+	e := newTraceExporter(Options{Service: "my-service"})
+	defer e.stop()
+
+	for name, tt := range map[string]struct {
+		lib     instrumentation.Library
+		kind    trace.SpanKind
+		op      string
+		version string
+	}{
+		"none": {
+			kind: trace.SpanKindServer,
+			op:   defaultOperationName,
+		},
+		"server": {
+			lib:     instrumentation.Library{Name: "otelhttp", Version: "0.11.0"},
+			kind:    trace.SpanKindServer,
+			op:      "otelhttp.server",
+			version: "0.11.0",
+		},
+		"unspecified": {
+			lib:  instrumentation.Library{Name: "worker"},
+			kind: trace.SpanKindUnspecified,
+			op:   "worker",
+		},
+		"normalized": {
+			lib:  instrumentation.Library{Name: "go.opentelemetry.io/contrib/otelgrpc"},
+			kind: trace.SpanKindClient,
+			op:   "go.opentelemetry.io_contrib_otelgrpc.client",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			sd := *spanPairs["child"].oc
+			sd.InstrumentationLibrary = tt.lib
+			sd.SpanKind = tt.kind
+			span := e.convertSpan(&sd)
+			eq(span.Name, tt.op)
+			eq(span.Meta[keyLibraryName], tt.lib.Name)
+			eq(span.Meta[keyLibraryVersion], tt.version)
+		})
+	}
+
+	t.Run("override", func(t *testing.T) {
+		sd := *spanPairs["child"].oc
+		sd.InstrumentationLibrary = instrumentation.Library{Name: "otelhttp"}
+		sd.Attributes = []label.KeyValue{label.String(keySpanName, "http.request")}
+		equalFunc(t)(e.convertSpan(&sd).Name, "http.request")
+	})
+}
+
+func TestSpanType(t *testing.T) {
+	for name, tt := range map[string]struct {
+		kind  trace.SpanKind
+		attrs []label.KeyValue
+		want  string
+	}{
+		"internal":   {kind: trace.SpanKindInternal, want: ""},
+		"server":     {kind: trace.SpanKindServer, want: "server"},
+		"client":     {kind: trace.SpanKindClient, want: "client"},
+		"web":        {kind: trace.SpanKindServer, attrs: []label.KeyValue{label.String(keyHTTPMethod, "GET")}, want: ext.SpanTypeWeb},
+		"http":       {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyHTTPMethod, "GET")}, want: ext.SpanTypeHTTP},
+		"sql":        {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyDBSystem, "postgresql")}, want: ext.SpanTypeSQL},
+		"cache":      {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyDBSystem, "redis")}, want: ext.AppTypeCache},
+		"db":         {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyDBSystem, "mongodb")}, want: ext.AppTypeDB},
+		"queue":      {kind: trace.SpanKindProducer, attrs: []label.KeyValue{label.String(keyMessagingSystem, "kafka")}, want: ext.SpanTypeMessageConsumer},
+		"rpc":        {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyRPCSystem, "grpc"), label.String(keyHTTPMethod, "POST")}, want: ext.AppTypeRPC},
+		"precedence": {kind: trace.SpanKindClient, attrs: []label.KeyValue{label.String(keyDBSystem, "mysql"), label.String(keyHTTPMethod, "GET")}, want: ext.SpanTypeSQL},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &export.SpanData{SpanKind: tt.kind, Attributes: tt.attrs}
+			equalFunc(t)(spanType(s), tt.want)
+		})
+	}
+
+	t.Run("explicit", func(t *testing.T) {
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		sd := *spanPairs["child"].oc
+		sd.Attributes = []label.KeyValue{
+			label.String(keyDBSystem, "redis"),
+			label.String(ext.SpanType, ext.SpanTypeRedis),
+		}
+		equalFunc(t)(e.convertSpan(&sd).Type, ext.SpanTypeRedis)
+	})
+}
+
+func TestTraceIDMapping(t *testing.T) {
+	t.Run("low64", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		span := e.convertSpan(spanPairs["child"].oc)
+		eq(span.TraceID, uint64(651345242494996240))
+		eq(span.traceIDHigh, uint64(72623859790382856))
+		eq(span.Meta[keyTraceIDHigh], "0102030405060708")
+	})
+
+	t.Run("low64-only", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{Service: "my-service"})
+		defer e.stop()
+		sd := *spanPairs["child"].oc
+		sd.SpanContext.TraceID = trace.ID([16]byte{15: 1})
+		span := e.convertSpan(&sd)
+		eq(span.TraceID, uint64(1))
+		eq(span.traceIDHigh, uint64(0))
+		_, ok := span.Meta[keyTraceIDHigh]
+		eq(ok, false)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{Service: "my-service", TraceIDMapping: TraceIDHash})
+		defer e.stop()
+		span := e.convertSpan(spanPairs["child"].oc)
+		if span.TraceID == 651345242494996240 || span.TraceID == 0 {
+			t.Fatalf("unexpected trace ID %d", span.TraceID)
+		}
+		eq(span.traceIDHigh, uint64(72623859790382856))
+		_, ok := span.Meta[keyTraceIDHigh]
+		eq(ok, false)
+
+		// the hash depends on the high bits too
+		sd := *spanPairs["child"].oc
+		sd.SpanContext.TraceID[0] = 0xff
+		if e.convertSpan(&sd).TraceID == span.TraceID {
+			t.Fatal("hash ignores the high bits")
+		}
+	})
+}
+
+func TestResourceAttributes(t *testing.T) {
+	res := resource.New(
+		label.String(ext.ServiceName, "res-service"),
+		label.String(keyDeploymentEnvironment, "res-env"),
+		label.String(keyServiceVersion, "res-version"),
+	)
+	for name, tt := range map[string]struct {
+		opts    Options
+		res     *resource.Resource
+		attrs   []label.KeyValue
+		service string
+		env     string
+		version string
+	}{
+		"options": {
+			opts:    Options{Service: "opt-service", Env: "opt-env", Version: "opt-version"},
+			service: "opt-service",
+			env:     "opt-env",
+			version: "opt-version",
+		},
+		"resource": {
+			opts:    Options{Service: "opt-service", Env: "opt-env", Version: "opt-version"},
+			res:     res,
+			service: "res-service",
+			env:     "res-env",
+			version: "res-version",
+		},
+		"attributes": {
+			opts: Options{Service: "opt-service", Env: "opt-env", Version: "opt-version"},
+			res:  res,
+			attrs: []label.KeyValue{
+				label.String(ext.ServiceName, "attr-service"),
+				label.String(keyDeploymentEnvironment, "attr-env"),
+				label.String(keyServiceVersion, "attr-version"),
+			},
+			service: "attr-service",
+			env:     "attr-env",
+			version: "attr-version",
+		},
+		"env": {
+			res:     res,
+			attrs:   []label.KeyValue{label.String(ext.Environment, "attr-env")},
+			service: "res-service",
+			env:     "attr-env",
+			version: "res-version",
+		},
+		"partial": {
+			opts:    Options{Service: "opt-service", Env: "opt-env"},
+			res:     resource.New(label.String(keyServiceVersion, "res-version")),
+			service: "opt-service",
+			env:     "opt-env",
+			version: "res-version",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			e := newTraceExporter(tt.opts)
+			defer e.stop()
+			sd := *spanPairs["child"].oc
+			sd.Resource = tt.res
+			sd.Attributes = tt.attrs
+			span := e.convertSpan(&sd)
+			eq(span.Service, tt.service)
+			eq(span.Meta[ext.Environment], tt.env)
+			eq(span.Meta[keyVersion], tt.version)
+		})
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	e := newTraceExporter(Options{
+		Service: "my-service",
+		ErrorClassifier: func(s *export.SpanData) (bool, string, string) {
+			switch s.StatusCode {
+			case codes.Canceled, codes.NotFound:
+				return false, "", ""
+			case codes.Unavailable:
+				return true, "", "retry later"
+			}
+			return DefaultErrorClassifier(s)
+		},
+	})
+	defer e.stop()
+
+	for name, tt := range map[string]struct {
+		kind    trace.SpanKind
+		code    codes.Code
+		err     int32
+		errType string
+		errMsg  string
+	}{
+		"canceled":    {kind: trace.SpanKindClient, code: codes.Canceled},
+		"not-found":   {kind: trace.SpanKindClient, code: codes.NotFound},
+		"unavailable": {kind: trace.SpanKindServer, code: codes.Unavailable, err: 1, errMsg: "retry later"},
+		"default":     {kind: trace.SpanKindServer, code: codes.Internal, err: 1, errType: "INTERNAL", errMsg: "status-msg"},
+		"ok":          {kind: trace.SpanKindServer, code: codes.OK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eq := equalFunc(t)
+			sd := *spanPairs["child"].oc
+			sd.SpanKind = tt.kind
+			sd.StatusCode = tt.code
+			sd.StatusMessage = "status-msg"
+			span := e.convertSpan(&sd)
+			eq(span.Error, tt.err)
+			eq(span.Meta[ext.ErrorType], tt.errType)
+			eq(span.Meta[ext.ErrorMsg], tt.errMsg)
+		})
+	}
+}
+
+func TestTopLevel(t *testing.T) {
+	for name, tt := range map[string]struct {
+		pair   string
+		remote bool
+		attrs  []label.KeyValue
+		want   bool
+	}{
+		"root":             {pair: "root", want: true},
+		"child":            {pair: "child"},
+		"remote-parent":    {pair: "child", remote: true, want: true},
+		"service-override": {pair: "child", attrs: []label.KeyValue{label.String(ext.ServiceName, "mysql")}},
+		"same-service":     {pair: "child", attrs: []label.KeyValue{label.String(ext.ServiceName, "my-service")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sd := *spanPairs[tt.pair].oc
+			sd.HasRemoteParent = tt.remote
+			sd.Attributes = tt.attrs
+			_, ok := ConvertSpan(&sd, Options{Service: "my-service"}).Metrics[keyTopLevel]
+			equalFunc(t)(ok, tt.want)
+		})
+	}
+}
+
+func TestSetTopLevel(t *testing.T) {
+	eq := equalFunc(t)
+	root := &Span{SpanID: 1, Service: "web", localRoot: true, Metrics: map[string]float64{keyTopLevel: 1}}
+	same := &Span{SpanID: 2, ParentID: 1, Service: "web", Metrics: map[string]float64{}}
+	other := &Span{SpanID: 3, ParentID: 2, Service: "db", Metrics: map[string]float64{}}
+	sameOverride := &Span{SpanID: 4, ParentID: 3, Service: "db", Metrics: map[string]float64{}}
+	orphan := &Span{SpanID: 5, ParentID: 9, Service: "db", Metrics: map[string]float64{}}
+	setTopLevel([]*Span{root, same, other, sameOverride, orphan})
+	eq(root.Metrics, map[string]float64{keyTopLevel: 1})
+	eq(same.Metrics, map[string]float64{})
+	eq(other.Metrics, map[string]float64{keyTopLevel: 1})
+	eq(sameOverride.Metrics, map[string]float64{})
+	eq(orphan.Metrics, map[string]float64{})
+}
+
+func TestMeasured(t *testing.T) {
+	measured := func(s *export.SpanData) bool { return s.SpanKind == trace.SpanKindClient }
+	for name, tt := range map[string]struct {
+		rule  func(*export.SpanData) bool
+		attrs []label.KeyValue
+		want  bool
+	}{
+		"none":            {},
+		"attribute":       {attrs: []label.KeyValue{label.Bool(keyMeasured, true)}, want: true},
+		"attribute-int":   {attrs: []label.KeyValue{label.Int(keyMeasured, 1)}, want: true},
+		"rule":            {rule: measured, want: true},
+		"rule-overridden": {rule: measured, attrs: []label.KeyValue{label.Bool(keyMeasured, false)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sd := *spanPairs["child"].oc
+			sd.Attributes = tt.attrs
+			span := ConvertSpan(&sd, Options{Measured: tt.rule})
+			_, ok := span.Metrics[keyMeasured]
+			equalFunc(t)(ok, tt.want)
+		})
+	}
+}
+
 func TestSetError(t *testing.T) {
 	for i, tt := range [...]struct {
 		val label.Value // error value
@@ -336,7 +676,7 @@ func TestSetError(t *testing.T) {
 		{val: label.Int64Value(0)},
 		{val: label.Float32Value(0), err: 1},
 	} {
-		span := &ddSpan{Meta: map[string]string{}}
+		span := &Span{Meta: map[string]string{}}
 		setError(span, tt.val)
 		if span.Error != tt.err {
 			t.Fatalf("%d: span.Error mismatch, wanted %d, got %d", i, tt.err, span.Error)
@@ -350,7 +690,7 @@ func TestSetError(t *testing.T) {
 }
 
 func TestSetStringTag(t *testing.T) {
-	span := &ddSpan{Meta: map[string]string{}}
+	span := &Span{Meta: map[string]string{}}
 	eq := equalFunc(t)
 
 	setStringTag(span, ext.ServiceName, "service")
@@ -367,8 +707,8 @@ func TestSetStringTag(t *testing.T) {
 }
 
 func TestSetTag(t *testing.T) {
-	testSpan := func() *ddSpan {
-		return &ddSpan{
+	testSpan := func() *Span {
+		return &Span{
 			Meta:    map[string]string{},
 			Metrics: map[string]float64{},
 		}
@@ -32,6 +32,8 @@ func testExporter(opts Options) (*testStatsExporter, error) {
 	if opts.OnError == nil {
 		opts.OnError = func(_ error) {}
 	}
+	// the statsd client reads the environment when created
+	defer clearDDEnv()()
 	e, err := NewExporter(opts)
 	if err != nil {
 		return nil, err
@@ -111,7 +113,9 @@ func TestDistributionData(t *testing.T) {
 
 	addr := conn.LocalAddr().String()
 
+	restoreEnv := clearDDEnv()
 	client, err := statsd.NewBuffered(addr, 100)
+	restoreEnv()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"container/list"
+	"time"
+)
+
+const (
+	// defaultTailSamplingTimeout specifies the default maximum duration for
+	// which the spans of a trace are buffered.
+	defaultTailSamplingTimeout = 30 * time.Second
+
+	// defaultTailSamplingMaxSpans specifies the default maximum number of
+	// spans buffered across all traces.
+	defaultTailSamplingMaxSpans = 100000
+)
+
+// bufferedTrace holds the spans of a trace received so far.
+type bufferedTrace struct {
+	key      traceKey
+	spans    []*Span
+	deadline time.Time // time after which the trace is released
+}
+
+// tailBuffer holds the spans of the traces whose local root span was not
+// received yet. It holds at most maxSpans spans, releasing the oldest traces
+// first, and releases the traces which have been buffered for longer than
+// timeout. It is not safe for concurrent use.
+type tailBuffer struct {
+	maxSpans int
+	timeout  time.Duration
+	traces   map[traceKey]*list.Element
+	order    *list.List // of *bufferedTrace, oldest first
+	spans    int        // number of buffered spans
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+func newTailBuffer(maxSpans int, timeout time.Duration) *tailBuffer {
+	return &tailBuffer{
+		maxSpans: maxSpans,
+		timeout:  timeout,
+		traces:   make(map[traceKey]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// add buffers the given span. When it is the local root span of its trace, it
+// releases the trace and returns its spans.
+func (b *tailBuffer) add(span *Span) []*Span {
+	key := traceKey{high: span.traceIDHigh, low: span.TraceID}
+	el, ok := b.traces[key]
+	if !ok {
+		if span.localRoot {
+			return []*Span{span}
+		}
+		el = b.order.PushBack(&bufferedTrace{key: key, deadline: b.now().Add(b.timeout)})
+		b.traces[key] = el
+	}
+	t := el.Value.(*bufferedTrace)
+	t.spans = append(t.spans, span)
+	b.spans++
+	if span.localRoot {
+		b.remove(el)
+		return t.spans
+	}
+	return nil
+}
+
+// release releases and returns the traces which timed out, as well as the
+// oldest ones when too many spans are buffered.
+func (b *tailBuffer) release() [][]*Span {
+	var released [][]*Span
+	now := b.now()
+	for el := b.order.Front(); el != nil; el = b.order.Front() {
+		t := el.Value.(*bufferedTrace)
+		if b.spans <= b.maxSpans && !now.After(t.deadline) {
+			break
+		}
+		b.remove(el)
+		released = append(released, t.spans)
+	}
+	return released
+}
+
+// drain releases and returns all of the buffered traces.
+func (b *tailBuffer) drain() [][]*Span {
+	var released [][]*Span
+	for el := b.order.Front(); el != nil; el = b.order.Front() {
+		b.remove(el)
+		released = append(released, el.Value.(*bufferedTrace).spans)
+	}
+	return released
+}
+
+// remove removes the given element from the buffer.
+func (b *tailBuffer) remove(el *list.Element) {
+	t := el.Value.(*bufferedTrace)
+	delete(b.traces, t.key)
+	b.order.Remove(el)
+	b.spans -= len(t.spans)
+}
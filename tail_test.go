@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestTailBuffer(t *testing.T) {
+	mkSpan := func(traceID, spanID uint64, root bool) *Span {
+		return &Span{TraceID: traceID, SpanID: spanID, localRoot: root}
+	}
+	now := time.Unix(100, 0)
+	newBuffer := func(maxSpans int) *tailBuffer {
+		b := newTailBuffer(maxSpans, time.Minute)
+		b.now = func() time.Time { return now }
+		return b
+	}
+	spanIDs := func(traces [][]*Span) [][]uint64 {
+		var ids [][]uint64
+		for _, spans := range traces {
+			var tids []uint64
+			for _, s := range spans {
+				tids = append(tids, s.SpanID)
+			}
+			ids = append(ids, tids)
+		}
+		return ids
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		eq := equalFunc(t)
+		b := newBuffer(10)
+		eq(b.add(mkSpan(1, 2, false)) == nil, true)
+		eq(b.add(mkSpan(2, 5, false)) == nil, true)
+		eq(b.add(mkSpan(1, 3, false)) == nil, true)
+		eq(b.spans, 3)
+		eq(spanIDs([][]*Span{b.add(mkSpan(1, 1, true))}), [][]uint64{{2, 3, 1}})
+		eq(b.spans, 1)
+		eq(spanIDs([][]*Span{b.add(mkSpan(3, 7, true))}), [][]uint64{{7}})
+		eq(b.spans, 1)
+		eq(len(b.release()), 0)
+		eq(spanIDs(b.drain()), [][]uint64{{5}})
+		eq(b.spans, 0)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		eq := equalFunc(t)
+		b := newBuffer(10)
+		b.add(mkSpan(1, 2, false))
+		now = now.Add(30 * time.Second)
+		b.add(mkSpan(2, 3, false))
+		now = now.Add(31 * time.Second)
+		eq(spanIDs(b.release()), [][]uint64{{2}})
+		eq(len(b.traces), 1)
+	})
+
+	t.Run("max-spans", func(t *testing.T) {
+		eq := equalFunc(t)
+		b := newBuffer(2)
+		b.add(mkSpan(1, 2, false))
+		b.add(mkSpan(2, 3, false))
+		b.add(mkSpan(2, 4, false))
+		eq(spanIDs(b.release()), [][]uint64{{2}})
+		eq(b.spans, 2)
+	})
+}
+
+func TestTailSampling(t *testing.T) {
+	rejectAll := `{"rate_by_service":{"service:,env:":0}}`
+
+	t.Run("whole-trace", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			Service:      "mock.exporter",
+			TailSampling: true,
+			Retention:    RetentionPolicy{KeepErrors: true},
+		})
+		eq(me.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(rejectAll))), nil)
+
+		child := *spanPairs["child"].oc
+		failed := child
+		failed.SpanContext.SpanID[0] = 42
+		failed.Attributes = []label.KeyValue{label.Bool(ext.Error, true)}
+		me.exportSpan(&child)
+		me.exportSpan(&failed)
+		me.exportSpan(spanPairs["root"].oc)
+		me.stop()
+
+		payloads := me.payloads()
+		eq(len(payloads), 1)
+		eq(len(payloads[0]), 1)
+		eq(len(payloads[0][0]), 3)
+		for _, span := range payloads[0][0] {
+			eq(span.Metrics[keySamplingPriority], float64(ext.PriorityUserKeep))
+			_, ok := span.Metrics[keySamplingPriorityRate]
+			eq(ok, span.ParentID == 0)
+		}
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			Service:      "mock.exporter",
+			TailSampling: true,
+		})
+		eq(me.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(rejectAll))), nil)
+		me.exportSpan(spanPairs["child"].oc)
+		me.stop()
+
+		payloads := me.payloads()
+		eq(len(payloads), 1)
+		eq(len(payloads[0]), 1)
+		eq(payloads[0][0][0].Metrics[keySamplingPriority], float64(ext.PriorityAutoReject))
+	})
+}
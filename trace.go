@@ -7,6 +7,7 @@ package datadog
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"sync"
 	"time"
@@ -23,6 +24,18 @@ const (
 	// with the registered traces. Users should normally specify a different
 	// service name.
 	defaultService = "opentelemetry-app"
+
+	// defaultMaxEvents specifies the default maximum number of span events
+	// exported with each span.
+	defaultMaxEvents = 128
+
+	// defaultMaxEventsSize specifies the default maximum size in bytes of the
+	// encoded span events exported with each span.
+	defaultMaxEventsSize = 25000
+
+	// defaultMaxLinksSize specifies the default maximum size in bytes of the
+	// encoded span links exported with each span.
+	defaultMaxLinksSize = 25000
 )
 
 // allows tests to override
@@ -41,33 +54,74 @@ var (
 )
 
 type traceExporter struct {
+	*spanConverter
+
 	opts    Options
 	payload *payload
 	errors  *errorAmortizer
 	sampler *prioritySampler
+	rules   *rulesSampler // nil when there are no sampling rules
+
+	// decisions holds the sampling decisions of the recent traces.
+	decisions   *decisionCache
+	retainer    *retainer    // nil when there is no retention policy
+	spanSampler *spanSampler // nil when there are no span sampling rules
+
+	// tail buffers spans until their trace is complete; nil unless tail
+	// sampling is enabled.
+	tail *tailBuffer
 
 	// uploadFn specifies the function used for uploading.
 	// Defaults to (*transport).upload; replaced in tests.
 	uploadFn func(pkg *bytes.Buffer, count int) (io.ReadCloser, error)
 
 	wg   sync.WaitGroup // counts active uploads
-	in   chan *ddSpan
+	in   chan *Span
 	exit chan struct{}
 }
 
 func newTraceExporter(o Options) *traceExporter {
-	if o.Service == "" {
-		o.Service = defaultService
+	conv := newSpanConverter(o)
+	o = conv.opts
+	if o.TraceRateLimit == 0 {
+		o.TraceRateLimit = defaultTraceRateLimit
+	}
+	if o.DecisionCacheSize <= 0 {
+		o.DecisionCacheSize = defaultDecisionCacheSize
+	}
+	if o.DecisionCacheTTL <= 0 {
+		o.DecisionCacheTTL = defaultDecisionCacheTTL
+	}
+	if o.TailSamplingMaxSpans <= 0 {
+		o.TailSamplingMaxSpans = defaultTailSamplingMaxSpans
+	}
+	if o.TailSamplingTimeout <= 0 {
+		o.TailSamplingTimeout = defaultTailSamplingTimeout
+	}
+	if o.Retention != (RetentionPolicy{}) && !o.TailSampling {
+		// upgrading the decision mid-trace would leave the spans which were
+		// already sent with a different priority
+		o.onError(errors.New("retention policy ignored: it requires TailSampling"))
+		o.Retention = RetentionPolicy{}
 	}
 	sampler := newPrioritySampler()
 	e := &traceExporter{
-		opts:     o,
-		payload:  newPayload(),
-		errors:   newErrorAmortizer(defaultErrorFreq, o.OnError),
-		sampler:  sampler,
-		uploadFn: newTransport(o.TraceAddr).upload,
-		in:       make(chan *ddSpan, inChannelSize),
-		exit:     make(chan struct{}),
+		spanConverter: conv,
+		opts:          o,
+		payload:       newPayload(),
+		errors:        newErrorAmortizer(defaultErrorFreq, o.OnError),
+		sampler:       sampler,
+		rules:         newRulesSampler(o.SamplingRules, o.TraceRateLimit, o.onError),
+		decisions:     newDecisionCache(o.DecisionCacheSize, o.DecisionCacheTTL),
+		retainer:      newRetainer(o.Retention),
+		spanSampler:   newSpanSampler(o.SpanSamplingRules, o.onError),
+		uploadFn:      newTransport(o.TraceAddr).upload,
+		in:            make(chan *Span, inChannelSize),
+		exit:          make(chan struct{}),
+	}
+
+	if o.TailSampling {
+		e.tail = newTailBuffer(o.TailSamplingMaxSpans, o.TailSamplingTimeout)
 	}
 
 	go e.loop()
@@ -99,6 +153,11 @@ loop:
 			e.receiveSpan(span)
 
 		case <-tick.C:
+			if e.tail != nil {
+				for _, spans := range e.tail.release() {
+					e.sendTrace(spans)
+				}
+			}
 			e.flush()
 
 		case <-e.exit:
@@ -116,17 +175,47 @@ loop:
 			drained = true
 		}
 	}
+	if e.tail != nil {
+		for _, spans := range e.tail.drain() {
+			e.sendTrace(spans)
+		}
+	}
 	e.flush()
 	e.wg.Wait() // wait for uploads to finish
 	e.errors.flush()
 }
 
-func (e *traceExporter) receiveSpan(span *ddSpan) {
-	if _, ok := span.Metrics[keySamplingPriority]; !ok {
-		e.sampler.applyPriority(span)
+func (e *traceExporter) receiveSpan(span *Span) {
+	for _, process := range e.opts.SpanProcessors {
+		if !process(span) {
+			e.errors.log(errorTypeDropped, nil)
+			return
+		}
+	}
+	if e.tail == nil {
+		e.sendTrace([]*Span{span})
+		return
+	}
+	if spans := e.tail.add(span); spans != nil {
+		e.sendTrace(spans)
+	}
+	for _, spans := range e.tail.release() {
+		e.sendTrace(spans)
+	}
+}
+
+// sendTrace samples the given spans, which belong to the same trace, and adds
+// them to the payload, flushing it when it grows too large.
+func (e *traceExporter) sendTrace(spans []*Span) {
+	if len(spans) > 1 {
+		setTopLevel(spans)
 	}
-	if err := e.payload.add(span); err != nil {
-		e.errors.log(errorTypeEncoding, err)
+	e.sampleTrace(spans)
+	for _, span := range spans {
+		e.spanSampler.apply(span)
+		if err := e.payload.add(span); err != nil {
+			e.errors.log(errorTypeEncoding, err)
+		}
 	}
 	if e.payload.size() > flushThreshold {
 		e.flush()
@@ -113,6 +113,47 @@ func TestTraceExporter(t *testing.T) {
 	})
 }
 
+func TestSpanProcessors(t *testing.T) {
+	eq := equalFunc(t)
+	var (
+		errs  []error
+		calls []string
+	)
+	me := newTestTraceExporterWithOptions(t, Options{
+		Service: "mock.exporter",
+		OnError: func(err error) { errs = append(errs, err) },
+		SpanProcessors: []SpanProcessor{
+			func(s *Span) bool {
+				calls = append(calls, "first")
+				s.Resource = "rewritten"
+				s.Meta["processed"] = "true"
+				return s.Type != "server"
+			},
+			func(s *Span) bool {
+				calls = append(calls, "second")
+				s.Service = "processed.service"
+				return true
+			},
+		},
+	})
+	me.exportSpan(spanPairs["root"].oc)             // client span, kept
+	me.exportSpan(spanPairs["server_error_5xx"].oc) // server span, dropped
+	me.stop()
+
+	eq(calls, []string{"first", "second", "first"})
+	payloads := me.payloads()
+	eq(len(payloads), 1)
+	eq(len(payloads[0]), 1)
+	eq(len(payloads[0][0]), 1)
+	span := payloads[0][0][0]
+	eq(span.Resource, "rewritten")
+	eq(span.Service, "processed.service")
+	eq(span.Meta["processed"], "true")
+
+	eq(len(errs), 1)
+	containsFunc(t)(errs[0], "span dropped by processor")
+}
+
 // testTraceExporter wraps a traceExporter, recording all flushed payloads.
 type testTraceExporter struct {
 	*traceExporter
@@ -123,7 +164,11 @@ type testTraceExporter struct {
 }
 
 func newTestTraceExporter(t *testing.T) *testTraceExporter {
-	te := newTraceExporter(Options{Service: "mock.exporter"})
+	return newTestTraceExporterWithOptions(t, Options{Service: "mock.exporter"})
+}
+
+func newTestTraceExporterWithOptions(t *testing.T, o Options) *testTraceExporter {
+	te := newTraceExporter(o)
 	me := &testTraceExporter{traceExporter: te, flushed: make([]ddPayload, 0)}
 	me.traceExporter.uploadFn = me.uploadFn
 	return me
@@ -20,7 +20,7 @@ func TestTransport(t *testing.T) {
 		t.Skip("to run: set the INTEGRATION environment variable and have the agent running")
 	}
 	p := newPayload()
-	for _, span := range []*ddSpan{
+	for _, span := range []*Span{
 		testSpan(1234, "abc", "qwe"),
 		testSpan(1234, "abc1", "qwe"),
 		testSpan(4567, "abc2", "qwe"),
@@ -41,11 +41,11 @@ func TestTransport(t *testing.T) {
 
 // testSpan returns a minimally valid span that the agent will accept
 // through its normalization process.
-func testSpan(traceID uint64, name, service string) *ddSpan {
+func testSpan(traceID uint64, name, service string) *Span {
 	now := time.Now()
 	start := now.UnixNano()
 	duration := now.Add(time.Minute).UnixNano() - start
-	return &ddSpan{
+	return &Span{
 		TraceID:  traceID,
 		SpanID:   atomic.AddUint64(&idSeed, 1),
 		Name:     name,